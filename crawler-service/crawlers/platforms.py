@@ -23,6 +23,7 @@ class PostData(BaseModel):
     tags: List[str] = []
     images: List[str] = []
     video_url: Optional[str] = None
+    is_fallback: bool = False  # 抓取失败时生成的占位内容
 
 class PlatformCrawler:
     """平台爬虫基类"""
@@ -341,7 +342,8 @@ class WeiboCrawler(PlatformCrawler):
                 url=f"https://weibo.com/search?q={quote(query)}",
                 published_at=datetime.now() - timedelta(hours=random.randint(1, 48)),
                 tags=["微博", "热门话题", query],
-                images=[]
+                images=[],
+                is_fallback=True
             )
             posts.append(post)
         return posts
@@ -576,7 +578,8 @@ class DouyinCrawler(PlatformCrawler):
                 url=f"https://www.douyin.com/search/{quote(query)}",
                 published_at=datetime.now() - timedelta(hours=random.randint(1, 48)),
                 tags=["抖音", "短视频", "热门", query],
-                images=[]
+                images=[],
+                is_fallback=True
             )
             posts.append(post)
         return posts
@@ -779,7 +782,8 @@ class XiaohongshuCrawler(PlatformCrawler):
                 url=f"https://www.xiaohongshu.com/search_result?keyword={quote(query)}",
                 published_at=datetime.now() - timedelta(hours=random.randint(1, 48)),
                 tags=["小红书", "种草", "生活分享", query],
-                images=[]
+                images=[],
+                is_fallback=True
             )
             posts.append(post)
         return posts
@@ -1250,7 +1254,8 @@ class NewsCrawler(PlatformCrawler):
                 url=f"https://www.baidu.com/s?wd={quote(query)}+新闻",
                 published_at=datetime.now() - timedelta(hours=random.randint(1, 24)),
                 tags=["新闻", news_type, query],
-                images=[]
+                images=[],
+                is_fallback=True
             )
             posts.append(post)
         return posts
@@ -177,6 +177,7 @@ class PostData(BaseModel):
     tags: List[str] = []
     images: List[str] = []
     video_url: Optional[str] = None
+    is_fallback: bool = False  # 抓取失败时生成的占位内容
 
 class PlatformCrawlRequest(BaseModel):
     creator_url: str  # Can be URL or search keywords
@@ -4559,7 +4560,8 @@ class UnifiedCrawlerService:
                 url=self._get_platform_url(platform, query),
                 published_at=datetime.now() - timedelta(hours=i+1),
                 tags=[platform, query],
-                images=[]
+                images=[],
+                is_fallback=True
             )
             posts.append(post)
         return posts
@@ -169,8 +169,8 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 	// 发布任务索引
 	publishTasksIndexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"video_id", 1}, {"platform", 1}},
-			Options: options.Index().SetUnique(true),
+			// 平台存储在platforms数组中，重复发布由CreatePublishTask检查
+			Keys: bson.D{{"video_id", 1}, {"platforms", 1}, {"status", 1}},
 		},
 		{
 			Keys:    bson.D{{"created_at", 1}},
@@ -207,19 +207,19 @@ func insertSampleData(db *mongo.Database) error {
 	sampleCreators := []interface{}{
 		Creator{
 			Username:  "tech_blogger",
-			Platform:  "微博",
+			Platform:  "weibo",
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
 		Creator{
 			Username:  "news_reporter",
-			Platform:  "抖音",
+			Platform:  "douyin",
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
 		Creator{
 			Username:  "lifestyle_vlogger",
-			Platform:  "小红书",
+			Platform:  "xiaohongshu",
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -32,6 +32,12 @@ type DatabaseConfig struct {
 type AppConfig struct {
 	Services ServiceConfig  `json:"services"`
 	Database DatabaseConfig `json:"database"`
+	Locale   string         `json:"locale"` // 生成文案的语言：zh（默认）、en
+	// PlatformAliases 额外的平台别名映射，键为别名，值为规范平台标识
+	PlatformAliases map[string]string `json:"platform_aliases"`
+	// Pagination 各资源（contents、tasks、posts、creators、files）的分页大小
+	Pagination map[string]PageSizeConfig `json:"pagination"`
+	Security   SecurityConfig            `json:"security"`
 }
 
 var Config *AppConfig
@@ -96,6 +102,7 @@ func getDefaultConfig() *AppConfig {
 				Database: "newshub",
 			},
 		},
+		Locale: "zh",
 	}
 }
 
@@ -130,3 +137,17 @@ func GetMongodbDatabase() string {
 	}
 	return Config.Database.MongoDB.Database
 }
+
+// GetLocale 获取生成文案使用的语言，环境变量APP_LOCALE优先
+func GetLocale() string {
+	if locale := os.Getenv("APP_LOCALE"); locale != "" {
+		return locale
+	}
+	if Config == nil {
+		LoadConfig()
+	}
+	if Config.Locale == "" {
+		return "zh"
+	}
+	return Config.Locale
+}
@@ -0,0 +1,28 @@
+package config
+
+// ContentLimits 爬取内容的字段长度限制（按字符计，0表示不限制）
+type ContentLimits struct {
+	MaxTitleLength   int
+	MaxContentLength int
+	KeepFullText     bool // 截断时是否将全文保存到 crawler_content_full 集合
+}
+
+// IsPostsMaterialized 帖子列表是否直接读取物化后的posts集合（环境变量 POSTS_MATERIALIZED）
+func IsPostsMaterialized() bool {
+	return GetEnvBool("POSTS_MATERIALIZED", false)
+}
+
+// GetContentHashNormalization 内容哈希的标准化步骤（环境变量 CONTENT_HASH_NORMALIZATION），
+// 逗号分隔的 html、lowercase、punctuation、whitespace，默认 exact 保持精确匹配
+func GetContentHashNormalization() string {
+	return getEnv("CONTENT_HASH_NORMALIZATION", "exact")
+}
+
+// GetContentLimits 获取爬取内容的字段长度限制
+func GetContentLimits() ContentLimits {
+	return ContentLimits{
+		MaxTitleLength:   GetEnvInt("CONTENT_MAX_TITLE_LENGTH", 200),
+		MaxContentLength: GetEnvInt("CONTENT_MAX_LENGTH", 5000),
+		KeepFullText:     getEnv("CONTENT_KEEP_FULL_TEXT", "true") == "true",
+	}
+}
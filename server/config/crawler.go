@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+// GetCrawlerTaskTimeout 爬取任务的默认超时时间（环境变量 CRAWLER_TASK_TIMEOUT，默认10分钟）
+func GetCrawlerTaskTimeout() time.Duration {
+	return GetEnvDuration("CRAWLER_TASK_TIMEOUT", 10*time.Minute)
+}
+
+// GetSearchEngineTimeout 进程内爬取时单个搜索引擎请求的超时时间（环境变量 CRAWLER_ENGINE_TIMEOUT，默认8秒）
+func GetSearchEngineTimeout() time.Duration {
+	return GetEnvDuration("CRAWLER_ENGINE_TIMEOUT", 8*time.Second)
+}
+
+// GetPythonCrawlTimeout 定时爬取时单次调用Python爬虫服务的超时时间（环境变量 CRAWLER_PYTHON_TIMEOUT，默认30秒，<=0 时使用默认值）
+func GetPythonCrawlTimeout() time.Duration {
+	if timeout := GetEnvDuration("CRAWLER_PYTHON_TIMEOUT", 30*time.Second); timeout > 0 {
+		return timeout
+	}
+	return 30 * time.Second
+}
+
+// GetCrawlerRawResultLimit 爬取任务保存的Python原始响应的最大字节数（环境变量 CRAWLER_RAW_RESULT_MAX_BYTES，默认256KB）
+func GetCrawlerRawResultLimit() int {
+	return GetEnvInt("CRAWLER_RAW_RESULT_MAX_BYTES", 256<<10)
+}
+
+// GetCreatorCrawlLimit 定时爬取创作者时每次请求的条数上限
+// 增量模式读取 CRAWLER_INCREMENTAL_LIMIT（默认20），全量模式读取 CRAWLER_FULL_LIMIT（默认200）
+func GetCreatorCrawlLimit(full bool) int {
+	if full {
+		return GetEnvInt("CRAWLER_FULL_LIMIT", 200)
+	}
+	return GetEnvInt("CRAWLER_INCREMENTAL_LIMIT", 20)
+}
+
+// GetCrawlerTaskCooldown 相同平台和创作者的任务完成后再次创建所需等待的时间（环境变量 CRAWLER_TASK_COOLDOWN，默认10分钟，0表示不限制）
+func GetCrawlerTaskCooldown() time.Duration {
+	return GetEnvDuration("CRAWLER_TASK_COOLDOWN", 10*time.Minute)
+}
+
+// CrawlerTaskDeadline 根据请求中的超时秒数（<=0 时使用默认值）计算任务截止时间
+func CrawlerTaskDeadline(start time.Time, timeoutSeconds int) time.Time {
+	timeout := GetCrawlerTaskTimeout()
+	if timeoutSeconds > 0 {
+		timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+	return start.Add(timeout)
+}
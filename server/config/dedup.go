@@ -0,0 +1,55 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// SimHash近似去重的默认配置
+const (
+	DefaultSimHashThreshold = 3
+	DefaultSimHashWindow    = 72 * time.Hour
+)
+
+// DedupWindow 去重比对的范围，0表示不限制
+type DedupWindow struct {
+	Days    int // 只与最近N天内入库的内容比对
+	Records int // 只与最近N条入库的内容比对
+}
+
+// GetDedupWindow 获取去重比对范围（环境变量 DEDUP_WINDOW_DAYS、DEDUP_WINDOW_RECORDS），默认不限制
+func GetDedupWindow() DedupWindow {
+	return DedupWindow{
+		Days:    GetEnvInt("DEDUP_WINDOW_DAYS", 0),
+		Records: GetEnvInt("DEDUP_WINDOW_RECORDS", 0),
+	}
+}
+
+// GetDedupTitleAuthorWindows 解析环境变量 DEDUP_TITLE_AUTHOR_WINDOWS（如 "default=24h,news=72h,weibo=6h"）
+func GetDedupTitleAuthorWindows() map[string]time.Duration {
+	windows := make(map[string]time.Duration)
+	for _, item := range strings.Split(getEnv("DEDUP_TITLE_AUTHOR_WINDOWS", ""), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			log.Printf("警告：忽略无效的去重窗口配置 %q", item)
+			continue
+		}
+		window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			log.Printf("警告：忽略无效的去重窗口配置 %q: %v", item, err)
+			continue
+		}
+		windows[strings.TrimSpace(parts[0])] = window
+	}
+	return windows
+}
+
+// GetDedupReprocessTimeout 后台重新去重任务的最长执行时间（环境变量 DEDUP_REPROCESS_TIMEOUT，默认10分钟）
+func GetDedupReprocessTimeout() time.Duration {
+	return GetEnvDuration("DEDUP_REPROCESS_TIMEOUT", 10*time.Minute)
+}
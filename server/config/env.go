@@ -0,0 +1,63 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"time"
+)
+
+// GetEnvInt 读取整数类型的环境变量，未设置或格式错误时返回默认值
+func GetEnvInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("警告：环境变量 %s=%q 不是有效的整数，使用默认值 %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvDuration 读取时长类型的环境变量（如 30s、5m），未设置或格式错误时返回默认值
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("警告：环境变量 %s=%q 不是有效的时长，使用默认值 %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvBool 读取布尔类型的环境变量（true/false/1/0），未设置或格式错误时返回默认值
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("警告：环境变量 %s=%q 不是有效的布尔值，使用默认值 %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvFloat 读取浮点类型的环境变量，未设置或格式错误时返回默认值
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("警告：环境变量 %s=%q 不是有效的数字，使用默认值 %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,34 @@
+package config
+
+// PageSizeConfig 单个资源的分页大小配置，0表示不限制
+type PageSizeConfig struct {
+	Default int `json:"default"`
+	Max     int `json:"max"`
+}
+
+// defaultPageSizes 各资源的默认分页大小
+var defaultPageSizes = map[string]PageSizeConfig{
+	"contents": {Default: 100, Max: 500},
+	"tasks":    {Default: 50, Max: 200},
+	"posts":    {Default: 50, Max: 200},
+	"creators": {Default: 0, Max: 1000},
+	"files":    {Default: 20, Max: 1000},
+}
+
+// GetPageSize 获取资源的分页大小配置，config.json 中 pagination 的设置优先
+func GetPageSize(resource string) PageSizeConfig {
+	if Config == nil {
+		LoadConfig()
+	}
+
+	size := defaultPageSizes[resource]
+	if override, ok := Config.Pagination[resource]; ok {
+		if override.Default > 0 {
+			size.Default = override.Default
+		}
+		if override.Max > 0 {
+			size.Max = override.Max
+		}
+	}
+	return size
+}
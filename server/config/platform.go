@@ -0,0 +1,69 @@
+package config
+
+import "strings"
+
+// defaultPlatformAliases 内置的平台别名，键为小写别名，值为规范平台标识
+var defaultPlatformAliases = map[string]string{
+	"weibo":       "weibo",
+	"微博":          "weibo",
+	"sina":        "weibo",
+	"douyin":      "douyin",
+	"抖音":          "douyin",
+	"xiaohongshu": "xiaohongshu",
+	"小红书":         "xiaohongshu",
+	"xhs":         "xiaohongshu",
+	"redbook":     "xiaohongshu",
+	"bilibili":    "bilibili",
+	"b站":          "bilibili",
+	"哔哩哔哩":        "bilibili",
+	"x":           "x",
+	"twitter":     "x",
+	"推特":          "x",
+	"youtube":     "youtube",
+	"油管":          "youtube",
+	"instagram":   "instagram",
+	"ins":         "instagram",
+	"tiktok":      "tiktok",
+}
+
+// platformAliases 返回合并了配置文件中 platform_aliases 的别名表
+func platformAliases() map[string]string {
+	if Config == nil {
+		LoadConfig()
+	}
+	if len(Config.PlatformAliases) == 0 {
+		return defaultPlatformAliases
+	}
+
+	aliases := make(map[string]string, len(defaultPlatformAliases)+len(Config.PlatformAliases))
+	for alias, canonical := range defaultPlatformAliases {
+		aliases[alias] = canonical
+	}
+	for alias, canonical := range Config.PlatformAliases {
+		aliases[strings.ToLower(strings.TrimSpace(alias))] = strings.ToLower(strings.TrimSpace(canonical))
+	}
+	return aliases
+}
+
+// NormalizePlatform 将平台名称（如 twitter、X、微博）转换为规范标识，未知平台返回小写后的原值
+func NormalizePlatform(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return ""
+	}
+	if canonical, ok := platformAliases()[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// IsKnownPlatform 判断平台名称经别名解析后是否为已知平台
+func IsKnownPlatform(name string) bool {
+	canonical := NormalizePlatform(name)
+	for _, value := range platformAliases() {
+		if value == canonical {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 限速后端
+const (
+	RateLimitBackendMemory = "memory" // 进程内计数，多副本部署时每个副本独立限速
+	RateLimitBackendRedis  = "redis"  // 通过Redis共享计数，所有副本共用同一限额
+)
+
+// GetRateLimitBackend 获取限速后端（环境变量 RATE_LIMIT_BACKEND），默认 memory
+func GetRateLimitBackend() string {
+	if getEnv("RATE_LIMIT_BACKEND", RateLimitBackendMemory) == RateLimitBackendRedis {
+		return RateLimitBackendRedis
+	}
+	return RateLimitBackendMemory
+}
+
+// NewRedisClient 按环境变量 REDIS_URL（如 redis://:password@localhost:6379/0）创建Redis客户端并检查连通性
+func NewRedisClient() (*redis.Client, error) {
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://localhost:6379/0"))
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// SecurityConfig CORS与安全响应头配置
+type SecurityConfig struct {
+	CORSOrigins []string          `json:"cors_origins"`
+	CSP         string            `json:"csp"`        // Content-Security-Policy，为"-"时不设置
+	CSPExempt   []string          `json:"csp_exempt"` // 不设置CSP的路由前缀（如视频、文件访问）
+	Headers     map[string]string `json:"headers"`    // 覆盖或追加的响应头，值为空字符串时移除该默认头
+}
+
+// GetSecurityConfig 获取安全配置，环境变量 CORS_ALLOWED_ORIGINS（逗号分隔）与 CONTENT_SECURITY_POLICY 优先
+func GetSecurityConfig() SecurityConfig {
+	if Config == nil {
+		LoadConfig()
+	}
+
+	cfg := Config.Security
+	if len(cfg.CORSOrigins) == 0 {
+		cfg.CORSOrigins = []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:3002"}
+	}
+	if cfg.CSP == "" {
+		cfg.CSP = "default-src 'self'; frame-ancestors 'none'"
+	}
+	if cfg.CSPExempt == nil {
+		cfg.CSPExempt = []string{"/api/videos/", "/api/storage/files/"}
+	}
+
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		cfg.CORSOrigins = nil
+		for _, origin := range strings.Split(origins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, origin)
+			}
+		}
+	}
+	if csp := os.Getenv("CONTENT_SECURITY_POLICY"); csp != "" {
+		cfg.CSP = csp
+	}
+
+	return cfg
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -22,4 +23,40 @@ func InitStorage() error {
 // GetVideoPath 获取视频文件的完整路径
 func GetVideoPath(videoId string) string {
 	return filepath.Join(VideoStoragePath, videoId+".mp4")
-}
\ No newline at end of file
+}
+
+// IsVideoPosterEnabled 是否为爬取到的视频内容生成封面图（需要安装ffmpeg）
+func IsVideoPosterEnabled() bool {
+	return getEnv("VIDEO_POSTER_ENABLED", "false") == "true"
+}
+
+// GetAllowedUploadFolders 允许上传的顶级文件夹（环境变量 STORAGE_ALLOWED_FOLDERS，逗号分隔）
+func GetAllowedUploadFolders() []string {
+	var folders []string
+	for _, folder := range strings.Split(getEnv("STORAGE_ALLOWED_FOLDERS", "images,videos,posters"), ",") {
+		if folder = strings.Trim(strings.TrimSpace(folder), "/"); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// GetUploadStreamThreshold 上传时在内存中缓冲的最大字节数（环境变量 UPLOAD_STREAM_THRESHOLD_MB，默认8MB）
+// 超过该大小的文件改为写入临时文件，避免大文件占满内存
+func GetUploadStreamThreshold() int64 {
+	mb := GetEnvInt("UPLOAD_STREAM_THRESHOLD_MB", 8)
+	if mb <= 0 {
+		mb = 8
+	}
+	return int64(mb) << 20
+}
+
+// GetMaxImageBytes 上传图片的最大字节数（环境变量 MAX_IMAGE_BYTES，默认10MB）
+func GetMaxImageBytes() int64 {
+	return int64(GetEnvInt("MAX_IMAGE_BYTES", 10<<20))
+}
+
+// GetMaxVideoBytes 上传视频的最大字节数（环境变量 MAX_VIDEO_BYTES，默认500MB）
+func GetMaxVideoBytes() int64 {
+	return int64(GetEnvInt("MAX_VIDEO_BYTES", 500<<20))
+}
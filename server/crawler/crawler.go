@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,26 +15,49 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"newshub/config"
 	"newshub/models"
+	"newshub/services"
+	"newshub/utils"
 )
 
 const PYTHON_CRAWLER_URL = "http://localhost:8001"
 
+var (
+	// ErrCreatorNotFound 创作者不存在
+	ErrCreatorNotFound = errors.New("创作者不存在")
+	// ErrCreatorCrawling 创作者正在爬取中
+	ErrCreatorCrawling = errors.New("创作者正在爬取中")
+)
+
 // ScheduledCrawlerService 智能定时爬虫服务
 type ScheduledCrawlerService struct {
-	db        *mongo.Database
-	isRunning bool
-	stopChan  chan bool
-	wg        sync.WaitGroup
+	db             *mongo.Database
+	isRunning      bool
+	stopChan       chan bool
+	wg             sync.WaitGroup
+	interval       time.Duration // 调度检查间隔
+	maxConcurrency int           // 每轮最大并发爬取数
+
+	// ctx 在Stop时取消，用于中断进行中的Python调用与重试等待
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// Python爬虫调用的重试策略：首次失败后依次等待1s、2s、4s重试
+const (
+	pythonCrawlMaxRetries  = 3
+	pythonCrawlBaseBackoff = time.Second
+)
+
 // CrawlRequest Python爬虫请求结构
 type CrawlRequest struct {
 	Platform   string `json:"platform"`
 	CreatorURL string `json:"creator_url"`
 	Limit      int    `json:"limit"`
+	Mode       string `json:"mode,omitempty"` // incremental 或 full，Python服务据此决定翻页深度
 }
 
 // CrawlResponse Python爬虫响应结构
@@ -44,16 +68,17 @@ type CrawlResponse struct {
 
 // PostData 爬取到的帖子数据
 type PostData struct {
-	Title       string    `json:"title"`
-	Content     string    `json:"content"`
-	Author      string    `json:"author"`
-	Platform    string    `json:"platform"`
-	URL         string    `json:"url"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Author      string     `json:"author"`
+	Platform    string     `json:"platform"`
+	URL         string     `json:"url"`
 	PublishedAt *time.Time `json:"published_at,omitempty"`
-	Tags        []string  `json:"tags"`
-	Images      []string  `json:"images"`
-	VideoURL    string    `json:"video_url,omitempty"`
-	OriginID    string    `json:"origin_id,omitempty"`
+	Tags        []string   `json:"tags"`
+	Images      []string   `json:"images"`
+	VideoURL    string     `json:"video_url,omitempty"`
+	OriginID    string     `json:"origin_id,omitempty"`
+	IsFallback  bool       `json:"is_fallback,omitempty"` // Python服务抓取失败时生成的备用内容
 }
 
 // UnmarshalJSON 自定义JSON解析，处理多种时间格式
@@ -95,9 +120,23 @@ func (p *PostData) UnmarshalJSON(data []byte) error {
 
 // NewScheduledCrawlerService 创建新的定时爬虫服务
 func NewScheduledCrawlerService() *ScheduledCrawlerService {
+	interval := config.GetEnvDuration("CRAWLER_SCHEDULE_INTERVAL", 30*time.Second)
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	maxConcurrency := config.GetEnvInt("CRAWLER_MAX_CONCURRENCY", 3)
+	if maxConcurrency <= 0 {
+		maxConcurrency = 3
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ScheduledCrawlerService{
-		db:       config.GetDB(),
-		stopChan: make(chan bool),
+		db:             config.GetDB(),
+		stopChan:       make(chan bool),
+		interval:       interval,
+		maxConcurrency: maxConcurrency,
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 }
 
@@ -128,6 +167,7 @@ func (scs *ScheduledCrawlerService) Stop() {
 	}
 
 	log.Println("⏹️ 停止定时爬虫服务...")
+	scs.cancel()
 	scs.stopChan <- true
 	scs.wg.Wait()
 	scs.isRunning = false
@@ -138,8 +178,8 @@ func (scs *ScheduledCrawlerService) Stop() {
 func (scs *ScheduledCrawlerService) schedulerLoop() {
 	defer scs.wg.Done()
 
-	// 每30秒检查一次是否有需要爬取的创作者
-	ticker := time.NewTicker(30 * time.Second)
+	// 定期检查是否有需要爬取的创作者（默认30秒）
+	ticker := time.NewTicker(scs.interval)
 	defer ticker.Stop()
 
 	for {
@@ -148,6 +188,7 @@ func (scs *ScheduledCrawlerService) schedulerLoop() {
 			log.Println("📝 收到停止信号，退出调度循环")
 			return
 		case <-ticker.C:
+			scs.failExpiredTasks()
 			scs.performScheduledCrawl()
 		}
 	}
@@ -173,7 +214,7 @@ func (scs *ScheduledCrawlerService) performScheduledCrawl() {
 	log.Printf("🎯 找到 %d 个创作者需要爬取", len(creatorsToProcess))
 
 	// 并发处理每个创作者（限制并发数）
-	semaphore := make(chan struct{}, 3) // 最多3个并发爬取任务
+	semaphore := make(chan struct{}, scs.maxConcurrency)
 	var wg sync.WaitGroup
 
 	for _, creator := range creatorsToProcess {
@@ -183,7 +224,7 @@ func (scs *ScheduledCrawlerService) performScheduledCrawl() {
 			semaphore <- struct{}{}        // 获取信号量
 			defer func() { <-semaphore }() // 释放信号量
 
-			scs.crawlCreatorContent(c)
+			scs.crawlCreatorContent(scs.ctx, c)
 		}(creator)
 	}
 
@@ -191,6 +232,35 @@ func (scs *ScheduledCrawlerService) performScheduledCrawl() {
 	log.Println("✅ 本轮爬取任务完成")
 }
 
+// failExpiredTasks 将超过截止时间仍未完成的爬取任务标记为失败
+func (scs *ScheduledCrawlerService) failExpiredTasks() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"status":   bson.M{"$in": []string{"pending", "running"}},
+		"deadline": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       "failed",
+			"error":        "任务超时：超过截止时间仍未完成",
+			"completed_at": now,
+			"updated_at":   now,
+		},
+	}
+
+	result, err := scs.db.Collection("crawler_tasks").UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Printf("❌ 处理超时任务失败: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("⏰ %d 个爬取任务已超时，标记为失败", result.ModifiedCount)
+	}
+}
+
 // getCreatorsReadyForCrawl 获取准备爬取的创作者
 func (scs *ScheduledCrawlerService) getCreatorsReadyForCrawl(ctx context.Context) ([]models.Creator, error) {
 	now := time.Now()
@@ -219,34 +289,65 @@ func (scs *ScheduledCrawlerService) getCreatorsReadyForCrawl(ctx context.Context
 	return creators, nil
 }
 
-// crawlCreatorContent 爬取指定创作者的内容
-func (scs *ScheduledCrawlerService) crawlCreatorContent(creator models.Creator) {
+// CrawlCreatorNow 立即爬取指定创作者（不等待next_crawl_at），返回新保存的帖子数
+func (scs *ScheduledCrawlerService) CrawlCreatorNow(ctx context.Context, creatorID primitive.ObjectID) (int, error) {
+	// 原子地将状态置为crawling，避免与定时任务重复爬取
+	var creator models.Creator
+	err := scs.db.Collection("creators").FindOneAndUpdate(ctx,
+		bson.M{"_id": creatorID, "crawl_status": bson.M{"$ne": "crawling"}},
+		bson.M{"$set": bson.M{"crawl_status": "crawling", "updated_at": time.Now()}},
+	).Decode(&creator)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := scs.db.Collection("creators").CountDocuments(ctx, bson.M{"_id": creatorID})
+		if countErr != nil {
+			return 0, countErr
+		}
+		if count == 0 {
+			return 0, ErrCreatorNotFound
+		}
+		return 0, ErrCreatorCrawling
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return scs.crawlCreatorContent(ctx, creator)
+}
+
+// crawlCreatorContent 爬取指定创作者的内容，返回新保存的帖子数
+func (scs *ScheduledCrawlerService) crawlCreatorContent(ctx context.Context, creator models.Creator) (int, error) {
 	log.Printf("🕷️ 开始爬取创作者: %s (%s)", creator.DisplayName, creator.Platform)
 
 	// 更新爬取状态
 	scs.updateCreatorCrawlStatus(creator.ID, "crawling", "")
 
-	// 准备爬取请求
+	// 准备爬取请求，全量模式用于回填历史内容
+	mode := creator.CrawlMode
+	if mode == "" {
+		mode = models.CrawlModeIncremental
+	}
 	crawlReq := CrawlRequest{
 		Platform:   creator.Platform,
 		CreatorURL: creator.ProfileURL,
-		Limit:      20, // 每次最多爬取20条
+		Limit:      config.GetCreatorCrawlLimit(mode == models.CrawlModeFull),
+		Mode:       mode,
 	}
 
 	// 调用Python爬虫服务
-	posts, err := scs.callPythonCrawler(crawlReq)
+	posts, err := scs.callPythonCrawler(ctx, crawlReq)
+	services.RecordCrawlOutcome(creator.Platform, services.ClassifyCrawlOutcome(err == nil, len(posts), countFallbackPosts(posts)))
 	if err != nil {
 		log.Printf("❌ 爬取 %s 失败: %v", creator.DisplayName, err)
 		scs.updateCreatorCrawlStatus(creator.ID, "failed", err.Error())
-		return
+		return 0, err
 	}
 
-	// 保存爬取结果（增量更新）
-	savedCount, err := scs.saveIncrementalPosts(creator.ID, posts)
+	// 保存爬取结果，全量模式会刷新已存在的帖子
+	savedCount, err := scs.saveIncrementalPosts(creator.ID, posts, mode)
 	if err != nil {
 		log.Printf("❌ 保存 %s 的内容失败: %v", creator.DisplayName, err)
 		scs.updateCreatorCrawlStatus(creator.ID, "failed", err.Error())
-		return
+		return 0, err
 	}
 
 	// 更新爬取状态和时间
@@ -256,36 +357,92 @@ func (scs *ScheduledCrawlerService) crawlCreatorContent(creator models.Creator)
 	scs.updateCreatorAfterCrawl(creator.ID, now, nextCrawl, savedCount)
 
 	log.Printf("✅ 完成爬取 %s: 新增 %d 条内容", creator.DisplayName, savedCount)
+	return savedCount, nil
 }
 
 // callPythonCrawler 调用Python爬虫服务
-func (scs *ScheduledCrawlerService) callPythonCrawler(req CrawlRequest) ([]PostData, error) {
+// 连接错误与5xx响应按指数退避重试，4xx响应不重试；ctx 取消时立即返回
+func (scs *ScheduledCrawlerService) callPythonCrawler(ctx context.Context, req CrawlRequest) ([]PostData, error) {
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	resp, err := http.Post(PYTHON_CRAWLER_URL+"/crawl/platform", "application/json", bytes.NewBuffer(reqBody))
+	backoff := pythonCrawlBaseBackoff
+	for attempt := 1; ; attempt++ {
+		log.Printf("调用Python爬虫服务: %s %s (第 %d 次)", req.Platform, req.CreatorURL, attempt)
+
+		posts, retryable, err := scs.doPythonCrawl(ctx, reqBody)
+		if err == nil {
+			return posts, nil
+		}
+		if !retryable || attempt > pythonCrawlMaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Printf("调用Python爬虫服务失败，%v 后重试: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// doPythonCrawl 发送一次爬取请求，返回错误是否可以重试；超时（CRAWLER_PYTHON_TIMEOUT）按可重试处理
+func (scs *ScheduledCrawlerService) doPythonCrawl(ctx context.Context, reqBody []byte) ([]PostData, bool, error) {
+	// 与手动触发共用全局调度名额，避免突发请求压垮Python服务
+	release, err := services.AcquirePythonDispatch(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
+
+	// 每次请求单独限时，避免Python服务无响应时一直占用调度名额
+	ctx, cancel := context.WithTimeout(ctx, config.GetPythonCrawlTimeout())
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, PYTHON_CRAWLER_URL+"/crawl/platform", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, false, fmt.Errorf("创建请求失败: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("调用Python爬虫服务失败: %v", err)
+		return nil, true, fmt.Errorf("调用Python爬虫服务失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Python爬虫服务返回错误: %d - %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("Python爬虫服务返回错误: %d - %s", resp.StatusCode, string(body))
+		return nil, resp.StatusCode >= http.StatusInternalServerError, err
 	}
 
 	var crawlResp CrawlResponse
 	if err := json.NewDecoder(resp.Body).Decode(&crawlResp); err != nil {
-		return nil, fmt.Errorf("解析爬虫响应失败: %v", err)
+		return nil, false, fmt.Errorf("解析爬虫响应失败: %v", err)
 	}
 
-	return crawlResp.Posts, nil
+	return crawlResp.Posts, false, nil
+}
+
+// countFallbackPosts 统计Python服务返回的备用内容条数
+func countFallbackPosts(posts []PostData) int {
+	count := 0
+	for _, post := range posts {
+		if post.IsFallback {
+			count++
+		}
+	}
+	return count
 }
 
-// saveIncrementalPosts 增量保存帖子（避免重复）
-func (scs *ScheduledCrawlerService) saveIncrementalPosts(creatorID primitive.ObjectID, posts []PostData) (int, error) {
+// saveIncrementalPosts 保存帖子，返回新增的帖子数
+// 增量模式跳过已存在的帖子；全量模式不跳过，用本次爬取的内容与媒体刷新已存在的帖子
+func (scs *ScheduledCrawlerService) saveIncrementalPosts(creatorID primitive.ObjectID, posts []PostData, mode string) (int, error) {
 	if len(posts) == 0 {
 		return 0, nil
 	}
@@ -298,7 +455,8 @@ func (scs *ScheduledCrawlerService) saveIncrementalPosts(creatorID primitive.Obj
 
 	for _, post := range posts {
 		// 生成内容哈希用于去重
-		contentHash := scs.generateContentHash(post.Title + "|" + post.Content)
+		// 与crawler_contents使用相同的哈希规则
+		contentHash := utils.TitleContentHash(post.Title, post.Content)
 
 		// 检查是否已存在
 		filter := bson.M{
@@ -306,12 +464,37 @@ func (scs *ScheduledCrawlerService) saveIncrementalPosts(creatorID primitive.Obj
 				{"content_hash": contentHash},
 				{"$and": []bson.M{
 					{"creator_id": creatorID},
-					{"origin_id": post.OriginID},
-					{"origin_id": bson.M{"$ne": ""}},
+					{"post_id": post.OriginID},
+					{"post_id": bson.M{"$ne": ""}},
 				}},
 			},
 		}
 
+		if mode == models.CrawlModeFull {
+			result, err := collection.UpdateOne(ctx, filter, bson.M{
+				"$set": bson.M{
+					"platform":     post.Platform,
+					"content":      post.Title + "\n" + post.Content,
+					"content_hash": contentHash,
+					"media_urls":   append(post.Images, post.VideoURL),
+				},
+				"$setOnInsert": bson.M{
+					"_id":        primitive.NewObjectID(),
+					"creator_id": creatorID,
+					"post_id":    post.OriginID,
+					"created_at": time.Now(),
+				},
+			}, options.Update().SetUpsert(true))
+			if err != nil {
+				log.Printf("保存帖子失败: %v", err)
+				continue
+			}
+			if result.UpsertedCount > 0 {
+				savedCount++
+			}
+			continue
+		}
+
 		count, err := collection.CountDocuments(ctx, filter)
 		if err != nil {
 			log.Printf("检查重复内容失败: %v", err)
@@ -324,13 +507,14 @@ func (scs *ScheduledCrawlerService) saveIncrementalPosts(creatorID primitive.Obj
 
 		// 创建新帖子
 		newPost := models.Post{
-			ID:        primitive.NewObjectID(),
-			CreatorID: creatorID,
-			Platform:  post.Platform,
-			PostID:    post.OriginID,
-			Content:   post.Title + "\n" + post.Content,
-			MediaURLs: append(post.Images, post.VideoURL),
-			CreatedAt: time.Now(),
+			ID:          primitive.NewObjectID(),
+			CreatorID:   creatorID,
+			Platform:    post.Platform,
+			PostID:      post.OriginID,
+			Content:     post.Title + "\n" + post.Content,
+			ContentHash: contentHash,
+			MediaURLs:   append(post.Images, post.VideoURL),
+			CreatedAt:   time.Now(),
 		}
 
 		_, err = collection.InsertOne(ctx, newPost)
@@ -357,10 +541,11 @@ func (scs *ScheduledCrawlerService) updateCreatorCrawlStatus(creatorID primitive
 		},
 	}
 
+	// 错误信息保留到下一次成功爬取或人工清除，便于排查
 	if errorMsg != "" {
 		update["$set"].(bson.M)["crawl_error"] = errorMsg
-	} else {
-		update["$unset"] = bson.M{"crawl_error": ""}
+		update["$set"].(bson.M)["crawl_error_at"] = time.Now()
+		update["$inc"] = bson.M{"crawl_failure_streak": 1}
 	}
 
 	scs.db.Collection("creators").UpdateOne(ctx, bson.M{"_id": creatorID}, update)
@@ -378,18 +563,8 @@ func (scs *ScheduledCrawlerService) updateCreatorAfterCrawl(creatorID primitive.
 			"next_crawl_at": nextCrawl,
 			"updated_at":    time.Now(),
 		},
-		"$unset": bson.M{"crawl_error": ""},
+		"$unset": bson.M{"crawl_error": "", "crawl_error_at": "", "crawl_failure_streak": ""},
 	}
 
 	scs.db.Collection("creators").UpdateOne(ctx, bson.M{"_id": creatorID}, update)
 }
-
-// generateContentHash 生成内容哈希
-func (scs *ScheduledCrawlerService) generateContentHash(content string) string {
-	// 这里可以使用更复杂的哈希算法
-	// 暂时使用简单的长度+前后字符组合
-	if len(content) < 10 {
-		return content
-	}
-	return fmt.Sprintf("%d_%s_%s", len(content), content[:5], content[len(content)-5:])
-}
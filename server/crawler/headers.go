@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 自定义请求头的限制
+const (
+	MaxCustomHeaders       = 20
+	maxCustomHeaderName    = 64
+	maxCustomHeaderValue   = 4096
+	maxCustomHeadersLength = 8192
+)
+
+// headerNamePattern HTTP头名称允许的字符（RFC 7230 token）
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// forbiddenHeaders 由HTTP客户端管理、不允许自定义的请求头
+var forbiddenHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Upgrade":           true,
+	"Te":                true,
+	"Trailer":           true,
+}
+
+// ValidateCustomHeaders 校验爬取任务的自定义请求头（如Referer、Cookie），返回名称规范化后的副本
+func ValidateCustomHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if len(headers) > MaxCustomHeaders {
+		return nil, fmt.Errorf("自定义请求头最多 %d 个", MaxCustomHeaders)
+	}
+
+	normalized := make(map[string]string, len(headers))
+	total := 0
+	for name, value := range headers {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 || len(name) > maxCustomHeaderName || !headerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的请求头名称: %q", name)
+		}
+		name = http.CanonicalHeaderKey(name)
+		if forbiddenHeaders[name] {
+			return nil, fmt.Errorf("不允许自定义请求头: %s", name)
+		}
+		if len(value) > maxCustomHeaderValue {
+			return nil, fmt.Errorf("请求头 %s 的值过长（最多 %d 字节）", name, maxCustomHeaderValue)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return nil, fmt.Errorf("请求头 %s 的值包含非法字符", name)
+		}
+
+		total += len(name) + len(value)
+		if total > maxCustomHeadersLength {
+			return nil, fmt.Errorf("自定义请求头总长度不能超过 %d 字节", maxCustomHeadersLength)
+		}
+		normalized[name] = value
+	}
+	return normalized, nil
+}
+
+// CustomHeaderNames 返回自定义请求头的名称（已排序），用于记录任务而不保存请求头的值
+func CustomHeaderNames(headers map[string]string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyCustomHeaders 将自定义请求头合并到请求中，覆盖同名的默认请求头
+func applyCustomHeaders(req *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+}
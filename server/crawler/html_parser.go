@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 结果块内标题链接与摘要的候选选择器，按优先级排列
+const (
+	resultTitleSelector       = "h3 a, h2 a, h4 a"
+	resultDescriptionSelector = ".c-abstract, .b_caption p, .b_lineclamp2, .content-right_8Zs40, .str_info, .space-txt, p"
+)
+
+// maxDescriptionRunes 从结果块文本推断摘要时保留的最大字符数
+const maxDescriptionRunes = 200
+
+// parseWithSelector 按搜索引擎的结果块选择器解析搜索结果，每个结果块提取标题、链接与摘要
+// baseURL 用于补全相对链接
+func parseWithSelector(html, selector, baseURL string) []SearchResult {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	base, _ := url.Parse(baseURL)
+
+	var results []SearchResult
+	doc.Find(selector).Each(func(_ int, block *goquery.Selection) {
+		link := block.Find(resultTitleSelector).First()
+		if link.Length() == 0 {
+			link = block.Find("a[href]").First()
+		}
+
+		title := normalizeSpace(link.Text())
+		href, _ := link.Attr("href")
+		href = resolveResultURL(base, strings.TrimSpace(href))
+		if title == "" || href == "" {
+			return
+		}
+
+		description := normalizeSpace(block.Find(resultDescriptionSelector).First().Text())
+		if description == "" {
+			// 没有摘要节点时取结果块中除标题外的文本
+			description = strings.TrimSpace(strings.Replace(normalizeSpace(block.Text()), title, "", 1))
+		}
+		if runes := []rune(description); len(runes) > maxDescriptionRunes {
+			description = string(runes[:maxDescriptionRunes])
+		}
+
+		results = append(results, SearchResult{
+			Title:       title,
+			URL:         href,
+			Description: description,
+		})
+	})
+
+	return results
+}
+
+// resolveResultURL 将相对链接补全为绝对链接，无法解析时返回空
+func resolveResultURL(base *url.URL, href string) string {
+	if href == "" || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base == nil || ref.IsAbs() {
+		return ref.String()
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// normalizeSpace 合并连续空白字符
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
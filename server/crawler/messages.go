@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"fmt"
+
+	"newshub/config"
+)
+
+// defaultLocale 默认语言，目录中缺失的文案也回退到该语言
+const defaultLocale = "zh"
+
+// messageCatalog 爬虫生成的默认文案（默认作者名、备用内容等），按语言区分
+var messageCatalog = map[string]map[string]string{
+	"zh": {
+		"platform.weibo":       "微博",
+		"platform.douyin":      "抖音",
+		"platform.xiaohongshu": "小红书",
+		"platform.bilibili":    "B站",
+		"platform.news":        "新闻",
+
+		"author.weibo":       "微博用户",
+		"author.douyin":      "抖音创作者",
+		"author.xiaohongshu": "小红书博主",
+		"author.bilibili":    "B站UP主",
+		"author.news":        "新闻编辑",
+		"author.default":     "创作者",
+
+		"query.default": "热门内容",
+
+		"fallback.title":   "%s热门话题：%s",
+		"fallback.content": "%s上关于'%s'的热门内容正在火热讨论中。",
+		"fallback.author":  "%s用户",
+		"fallback.tag":     "热门",
+
+		"news.type.0":  "突发",
+		"news.type.1":  "深度",
+		"news.type.2":  "分析",
+		"news.type.3":  "评论",
+		"news.type.4":  "报道",
+		"news.title":   "%s：%s最新进展",
+		"news.content": "关于'%s'的%s新闻报道，详细分析了相关事件的背景、影响和发展趋势。",
+	},
+	"en": {
+		"platform.weibo":       "Weibo",
+		"platform.douyin":      "Douyin",
+		"platform.xiaohongshu": "Xiaohongshu",
+		"platform.bilibili":    "Bilibili",
+		"platform.news":        "News",
+
+		"author.weibo":       "Weibo user",
+		"author.douyin":      "Douyin creator",
+		"author.xiaohongshu": "Xiaohongshu blogger",
+		"author.bilibili":    "Bilibili uploader",
+		"author.news":        "News editor",
+		"author.default":     "Creator",
+
+		"query.default": "trending",
+
+		"fallback.title":   "Trending on %s: %s",
+		"fallback.content": "Posts about '%[2]s' are trending on %[1]s right now.",
+		"fallback.author":  "%s user",
+		"fallback.tag":     "trending",
+
+		"news.type.0":  "Breaking",
+		"news.type.1":  "In-depth",
+		"news.type.2":  "Analysis",
+		"news.type.3":  "Opinion",
+		"news.type.4":  "Report",
+		"news.title":   "%s: latest on %s",
+		"news.content": "A %[2]s report on '%[1]s', covering the background, impact and outlook of the story.",
+	},
+}
+
+// message 获取当前语言下的文案，缺失时回退到默认语言
+func message(key string) string {
+	if messages, ok := messageCatalog[config.GetLocale()]; ok {
+		if text, ok := messages[key]; ok {
+			return text
+		}
+	}
+	return messageCatalog[defaultLocale][key]
+}
+
+// messagef 获取并格式化当前语言下的文案
+func messagef(key string, args ...interface{}) string {
+	return fmt.Sprintf(message(key), args...)
+}
+
+// platformDisplayName 获取平台在当前语言下的显示名称
+func platformDisplayName(platform string) string {
+	if name := message("platform." + platform); name != "" {
+		return name
+	}
+	return platform
+}
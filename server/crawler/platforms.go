@@ -1,9 +1,11 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -12,7 +14,9 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"newshub/config"
 	"newshub/models"
+	"newshub/services"
 )
 
 // SearchEngine 搜索引擎配置
@@ -29,7 +33,7 @@ type PlatformConfig struct {
 	Keywords      []string
 }
 
-// 平台配置
+// 平台配置，运行期间通过 RegisterPlatform、RegisterSearchEngine 修改，读取需经 GetPlatformConfig
 var platformConfigs = map[string]PlatformConfig{
 	"weibo": {
 		Name: "weibo",
@@ -81,7 +85,7 @@ type SearchResult struct {
 // CrawlWeiboPosts 爬取微博内容
 func CrawlWeiboPosts(creator models.Creator) ([]models.Post, error) {
 	query := extractQueryFromCreator(creator)
-	contents, err := crawlPlatformContent("weibo", query, 10)
+	contents, err := crawlPlatformContent(context.Background(), "weibo", query, 10, nil)
 	if err != nil {
 		return createFallbackPosts("weibo", creator, query, 3), nil
 	}
@@ -106,7 +110,7 @@ func CrawlWeiboPosts(creator models.Creator) ([]models.Post, error) {
 // CrawlDouyinPosts 爬取抖音内容
 func CrawlDouyinPosts(creator models.Creator) ([]models.Post, error) {
 	query := extractQueryFromCreator(creator)
-	contents, err := crawlPlatformContent("douyin", query, 10)
+	contents, err := crawlPlatformContent(context.Background(), "douyin", query, 10, nil)
 	if err != nil {
 		return createFallbackPosts("douyin", creator, query, 3), nil
 	}
@@ -135,7 +139,7 @@ func CrawlDouyinPosts(creator models.Creator) ([]models.Post, error) {
 // CrawlXiaohongshuPosts 爬取小红书内容
 func CrawlXiaohongshuPosts(creator models.Creator) ([]models.Post, error) {
 	query := extractQueryFromCreator(creator)
-	contents, err := crawlPlatformContent("xiaohongshu", query, 10)
+	contents, err := crawlPlatformContent(context.Background(), "xiaohongshu", query, 10, nil)
 	if err != nil {
 		return createFallbackPosts("xiaohongshu", creator, query, 3), nil
 	}
@@ -159,7 +163,7 @@ func CrawlXiaohongshuPosts(creator models.Creator) ([]models.Post, error) {
 // CrawlBilibiliPosts 爬取B站内容
 func CrawlBilibiliPosts(creator models.Creator) ([]models.Post, error) {
 	query := extractQueryFromCreator(creator)
-	contents, err := crawlPlatformContent("bilibili", query, 10)
+	contents, err := crawlPlatformContent(context.Background(), "bilibili", query, 10, nil)
 	if err != nil {
 		return createFallbackPosts("bilibili", creator, query, 3), nil
 	}
@@ -187,7 +191,7 @@ func CrawlBilibiliPosts(creator models.Creator) ([]models.Post, error) {
 
 // CrawlNewsPosts 爬取新闻内容
 func CrawlNewsPosts(query string, limit int) ([]models.Post, error) {
-	contents, err := crawlNewsContent(query, limit)
+	contents, err := crawlNewsContent(context.Background(), query, limit, nil)
 	if err != nil {
 		return createFallbackNews(query, limit), nil
 	}
@@ -215,8 +219,9 @@ func CrawlNewsPosts(query string, limit int) ([]models.Post, error) {
 }
 
 // CrawlPlatformContentAdvanced 高级爬取接口，返回详细的CrawlerContent
-func CrawlPlatformContentAdvanced(platform, query string, limit int, taskID primitive.ObjectID) ([]models.CrawlerContent, error) {
-	contents, err := crawlPlatformContent(platform, query, limit)
+// headers 为本次爬取附加的请求头（需先经ValidateCustomHeaders校验），可为nil
+func CrawlPlatformContentAdvanced(ctx context.Context, platform, query string, limit int, taskID primitive.ObjectID, headers map[string]string) ([]models.CrawlerContent, error) {
+	contents, err := crawlPlatformContent(ctx, platform, query, limit, headers)
 	if err != nil {
 		return createFallbackContent(platform, query, limit, taskID), nil
 	}
@@ -229,29 +234,44 @@ func CrawlPlatformContentAdvanced(platform, query string, limit int, taskID prim
 	return contents, nil
 }
 
+// skipHealthRecordKey 标记本次爬取不计入健康度统计的context键
+type skipHealthRecordKey struct{}
+
+// CrawlPlatformContentRaw 执行进程内爬取但不使用备用内容，解析不到结果时返回空列表，用于调试
+// 调试爬取不计入爬取健康度统计
+func CrawlPlatformContentRaw(ctx context.Context, platform, query string, limit int, headers map[string]string) ([]models.CrawlerContent, error) {
+	ctx = context.WithValue(ctx, skipHealthRecordKey{}, true)
+	return crawlPlatformContent(ctx, platform, query, limit, headers)
+}
+
 // crawlPlatformContent 爬取平台内容的通用方法
-func crawlPlatformContent(platform, query string, limit int) ([]models.CrawlerContent, error) {
-	config, exists := platformConfigs[platform]
+// 每个搜索引擎单独限时；ctx 取消时停止后续引擎，返回已收集到的结果
+func crawlPlatformContent(ctx context.Context, platform, query string, limit int, headers map[string]string) ([]models.CrawlerContent, error) {
+	platformConfig, exists := GetPlatformConfig(platform)
 	if !exists {
 		return nil, fmt.Errorf("不支持的平台: %s", platform)
 	}
 
 	var allResults []SearchResult
+	attempted, failed := 0, 0
 
-	for _, engine := range config.SearchEngines {
-		if len(allResults) >= limit {
+	for _, engine := range platformConfig.SearchEngines {
+		if len(allResults) >= limit || ctx.Err() != nil {
 			break
 		}
 
 		searchURL := fmt.Sprintf(engine.BaseURL, url.QueryEscape(query))
-		results, err := performSearch(searchURL)
+		results, err := searchWithTimeout(ctx, engine, searchURL, headers)
+		attempted++
 		if err != nil {
+			log.Printf("搜索引擎 %s 请求失败: %v", engine.Name, err)
+			failed++
 			continue
 		}
 
 		// 过滤平台相关结果
 		for _, result := range results {
-			if isPlatformRelated(result, config.Keywords, query) {
+			if isPlatformRelated(result, platformConfig.Keywords, query) {
 				allResults = append(allResults, result)
 				if len(allResults) >= limit {
 					break
@@ -285,11 +305,12 @@ func crawlPlatformContent(platform, query string, limit int) ([]models.CrawlerCo
 		contents = append(contents, content)
 	}
 
+	recordSearchOutcome(ctx, platform, len(contents), attempted, failed)
 	return contents, nil
 }
 
-// crawlNewsContent 爬取新闻内容
-func crawlNewsContent(query string, limit int) ([]models.CrawlerContent, error) {
+// crawlNewsContent 爬取新闻内容，超时与取消的处理同 crawlPlatformContent
+func crawlNewsContent(ctx context.Context, query string, limit int, headers map[string]string) ([]models.CrawlerContent, error) {
 	newsSearchEngines := []SearchEngine{
 		{Name: "baidu", BaseURL: "https://www.baidu.com/s?wd=%s+新闻", Selector: ".result.c-container"},
 		{Name: "sogou", BaseURL: "https://www.sogou.com/web?query=%s+最新消息", Selector: ".result"},
@@ -297,15 +318,19 @@ func crawlNewsContent(query string, limit int) ([]models.CrawlerContent, error)
 	}
 
 	var allResults []SearchResult
+	attempted, failed := 0, 0
 
 	for _, engine := range newsSearchEngines {
-		if len(allResults) >= limit {
+		if len(allResults) >= limit || ctx.Err() != nil {
 			break
 		}
 
 		searchURL := fmt.Sprintf(engine.BaseURL, url.QueryEscape(query))
-		results, err := performSearch(searchURL)
+		results, err := searchWithTimeout(ctx, engine, searchURL, headers)
+		attempted++
 		if err != nil {
+			log.Printf("搜索引擎 %s 请求失败: %v", engine.Name, err)
+			failed++
 			continue
 		}
 
@@ -345,14 +370,39 @@ func crawlNewsContent(query string, limit int) ([]models.CrawlerContent, error)
 		contents = append(contents, content)
 	}
 
+	recordSearchOutcome(ctx, "news", len(contents), attempted, failed)
 	return contents, nil
 }
 
-// performSearch 执行搜索请求（使用标准库HTML解析）
-func performSearch(searchURL string) ([]SearchResult, error) {
+// recordSearchOutcome 记录一次进程内爬取的结果到健康度统计，所有搜索引擎都失败时记为错误
+// 通过 CrawlPlatformContentRaw 发起的调试爬取不记录
+func recordSearchOutcome(ctx context.Context, platform string, count, attempted, failed int) {
+	if skip, _ := ctx.Value(skipHealthRecordKey{}).(bool); skip {
+		return
+	}
+	switch {
+	case count > 0:
+		services.RecordCrawlOutcome(platform, services.CrawlOutcomeReal)
+	case attempted > 0 && failed == attempted:
+		services.RecordCrawlOutcome(platform, services.CrawlOutcomeError)
+	default:
+		services.RecordCrawlOutcome(platform, services.CrawlOutcomeEmpty)
+	}
+}
+
+// searchWithTimeout 以单个搜索引擎的超时时间（CRAWLER_ENGINE_TIMEOUT）执行搜索，避免一个慢引擎拖住整次爬取
+func searchWithTimeout(ctx context.Context, engine SearchEngine, searchURL string, headers map[string]string) ([]SearchResult, error) {
+	engineCtx, cancel := context.WithTimeout(ctx, config.GetSearchEngineTimeout())
+	defer cancel()
+	return performSearch(engineCtx, engine, searchURL, headers)
+}
+
+// performSearch 执行搜索请求，优先按引擎的结果块选择器解析
+// headers 为本次爬取的自定义请求头，覆盖同名的默认请求头
+func performSearch(ctx context.Context, engine SearchEngine, searchURL string, headers map[string]string) ([]SearchResult, error) {
 	client := createHTTPClient()
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -362,6 +412,7 @@ func performSearch(searchURL string) ([]SearchResult, error) {
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("Cache-Control", "no-cache")
+	applyCustomHeaders(req, headers)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -378,7 +429,12 @@ func performSearch(searchURL string) ([]SearchResult, error) {
 		return nil, err
 	}
 
-	// 使用正则表达式简单解析HTML内容
+	// 选择器未匹配到结果时（页面结构变化），退回正则表达式解析
+	if engine.Selector != "" {
+		if results := parseWithSelector(string(body), engine.Selector, searchURL); len(results) > 0 {
+			return results, nil
+		}
+	}
 	return parseSearchResults(string(body)), nil
 }
 
@@ -505,19 +561,11 @@ func extractAuthor(result SearchResult, platform string) string {
 	}
 
 	// 默认作者名
-	defaultAuthors := map[string]string{
-		"weibo":       "微博用户",
-		"douyin":      "抖音创作者",
-		"xiaohongshu": "小红书博主",
-		"bilibili":    "B站UP主",
-		"news":        "新闻编辑",
-	}
-
-	if author, exists := defaultAuthors[platform]; exists {
+	if author := message("author." + platform); author != "" {
 		return author
 	}
 
-	return "创作者"
+	return message("author.default")
 }
 
 // extractTags 提取标签
@@ -580,21 +628,15 @@ func extractQueryFromCreator(creator models.Creator) string {
 	if creator.Username != "" {
 		return creator.Username
 	}
-	return "热门内容"
+	return message("query.default")
 }
 
 // createFallbackPosts 创建备用帖子
 func createFallbackPosts(platform string, creator models.Creator, query string, limit int) []models.Post {
+	services.RecordCrawlOutcome(platform, services.CrawlOutcomeFallback)
 	var posts []models.Post
 
-	platformNames := map[string]string{
-		"weibo":       "微博",
-		"douyin":      "抖音",
-		"xiaohongshu": "小红书",
-		"bilibili":    "B站",
-	}
-
-	platformName := platformNames[platform]
+	platformName := platformDisplayName(platform)
 
 	for i := 0; i < limit; i++ {
 		post := models.Post{
@@ -602,7 +644,7 @@ func createFallbackPosts(platform string, creator models.Creator, query string,
 			CreatorID: creator.ID,
 			Platform:  platform,
 			PostID:    fmt.Sprintf("%s_%d_%d", platform, time.Now().Unix(), i),
-			Content:   fmt.Sprintf("%s热门话题：%s\n%s上关于'%s'的热门内容正在火热讨论中。", platformName, query, platformName, query),
+			Content:   messagef("fallback.title", platformName, query) + "\n" + messagef("fallback.content", platformName, query),
 			MediaURLs: []string{},
 			CreatedAt: time.Now().Add(-time.Duration(i+1) * time.Hour),
 		}
@@ -614,17 +656,18 @@ func createFallbackPosts(platform string, creator models.Creator, query string,
 
 // createFallbackNews 创建备用新闻
 func createFallbackNews(query string, limit int) []models.Post {
+	services.RecordCrawlOutcome("news", services.CrawlOutcomeFallback)
 	var posts []models.Post
-	newsTypes := []string{"突发", "深度", "分析", "评论", "报道"}
+	newsTypeCount := 5
 
-	for i := 0; i < limit && i < len(newsTypes); i++ {
-		newsType := newsTypes[i]
+	for i := 0; i < limit && i < newsTypeCount; i++ {
+		newsType := message(fmt.Sprintf("news.type.%d", i))
 		post := models.Post{
 			ID:        primitive.NewObjectID(),
 			CreatorID: primitive.NilObjectID,
 			Platform:  "news",
 			PostID:    fmt.Sprintf("news_%d_%d", time.Now().Unix(), i),
-			Content:   fmt.Sprintf("%s：%s最新进展\n关于'%s'的%s新闻报道，详细分析了相关事件的背景、影响和发展趋势。", newsType, query, query, newsType),
+			Content:   messagef("news.title", newsType, query) + "\n" + messagef("news.content", query, newsType),
 			MediaURLs: []string{},
 			CreatedAt: time.Now().Add(-time.Duration(i+1) * time.Hour),
 		}
@@ -636,30 +679,23 @@ func createFallbackNews(query string, limit int) []models.Post {
 
 // createFallbackContent 创建备用内容
 func createFallbackContent(platform, query string, limit int, taskID primitive.ObjectID) []models.CrawlerContent {
+	services.RecordCrawlOutcome(platform, services.CrawlOutcomeFallback)
 	var contents []models.CrawlerContent
 
-	platformNames := map[string]string{
-		"weibo":       "微博",
-		"douyin":      "抖音",
-		"xiaohongshu": "小红书",
-		"bilibili":    "B站",
-		"news":        "新闻",
-	}
-
-	platformName := platformNames[platform]
+	platformName := platformDisplayName(platform)
 
 	for i := 0; i < limit; i++ {
 		publishedAt := time.Now().Add(-time.Duration(i+1) * time.Hour)
 		content := models.CrawlerContent{
 			ID:          primitive.NewObjectID(),
 			TaskID:      taskID,
-			Title:       fmt.Sprintf("%s热门话题：%s", platformName, query),
-			Content:     fmt.Sprintf("%s上关于'%s'的热门内容正在火热讨论中。", platformName, query),
-			Author:      fmt.Sprintf("%s用户", platformName),
+			Title:       messagef("fallback.title", platformName, query),
+			Content:     messagef("fallback.content", platformName, query),
+			Author:      messagef("fallback.author", platformName),
 			Platform:    platform,
 			URL:         fmt.Sprintf("https://www.baidu.com/s?wd=%s", url.QueryEscape(query)),
 			PublishedAt: &publishedAt,
-			Tags:        []string{platformName, "热门", query},
+			Tags:        []string{platformName, message("fallback.tag"), query},
 			Images:      []string{},
 			VideoURL:    "",
 			CreatedAt:   time.Now(),
@@ -693,9 +729,7 @@ func makeRequest(url string, headers map[string]string) ([]byte, error) {
 
 	// 设置请求头
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
+	applyCustomHeaders(req, headers)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// platformMutex 保护 platformConfigs，注册可能发生在运行期间
+var platformMutex sync.RWMutex
+
+// RegisterPlatform 注册或替换一个平台的搜索配置
+func RegisterPlatform(name string, cfg PlatformConfig) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("平台名称不能为空")
+	}
+	for _, engine := range cfg.SearchEngines {
+		if err := validateSearchEngine(engine); err != nil {
+			return err
+		}
+	}
+
+	cfg.Name = name
+	cfg.SearchEngines = append([]SearchEngine(nil), cfg.SearchEngines...)
+	cfg.Keywords = append([]string(nil), cfg.Keywords...)
+
+	platformMutex.Lock()
+	defer platformMutex.Unlock()
+	platformConfigs[name] = cfg
+	return nil
+}
+
+// RegisterSearchEngine 为已注册的平台添加搜索引擎，同名引擎会被替换
+func RegisterSearchEngine(platform string, engine SearchEngine) error {
+	if err := validateSearchEngine(engine); err != nil {
+		return err
+	}
+
+	platformMutex.Lock()
+	defer platformMutex.Unlock()
+
+	cfg, exists := platformConfigs[platform]
+	if !exists {
+		return fmt.Errorf("不支持的平台: %s", platform)
+	}
+
+	// 复制切片，避免修改正在被爬取读取的配置
+	engines := make([]SearchEngine, 0, len(cfg.SearchEngines)+1)
+	replaced := false
+	for _, existing := range cfg.SearchEngines {
+		if existing.Name == engine.Name {
+			existing = engine
+			replaced = true
+		}
+		engines = append(engines, existing)
+	}
+	if !replaced {
+		engines = append(engines, engine)
+	}
+	cfg.SearchEngines = engines
+	platformConfigs[platform] = cfg
+	return nil
+}
+
+// ListPlatforms 返回已注册的平台名称（按名称排序）
+func ListPlatforms() []string {
+	platformMutex.RLock()
+	defer platformMutex.RUnlock()
+
+	names := make([]string, 0, len(platformConfigs))
+	for name := range platformConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPlatformConfig 获取平台的搜索配置
+func GetPlatformConfig(name string) (PlatformConfig, bool) {
+	platformMutex.RLock()
+	defer platformMutex.RUnlock()
+
+	cfg, exists := platformConfigs[name]
+	return cfg, exists
+}
+
+// validateSearchEngine 校验搜索引擎配置，BaseURL 需包含一个 %s 作为查询词占位符
+func validateSearchEngine(engine SearchEngine) error {
+	if strings.TrimSpace(engine.Name) == "" {
+		return fmt.Errorf("搜索引擎名称不能为空")
+	}
+	if strings.Count(engine.BaseURL, "%s") != 1 {
+		return fmt.Errorf("搜索引擎 %s 的BaseURL必须包含一个%%s占位符", engine.Name)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"newshub/services"
+)
+
+// GetCrawlHealth 获取各平台最近一段时间的爬取健康度，真实内容比例过低的平台标记为降级
+func GetCrawlHealth(c *gin.Context) {
+	tracker := services.GetCrawlHealthTracker()
+	platforms := tracker.Snapshot()
+
+	var degraded []string
+	for _, platform := range platforms {
+		if platform.Degraded {
+			degraded = append(degraded, platform.Platform)
+		}
+	}
+	if degraded == nil {
+		degraded = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":         tracker.Window().String(),
+		"min_real_ratio": tracker.MinRealRatio(),
+		"platforms":      platforms,
+		"degraded":       degraded,
+	})
+}
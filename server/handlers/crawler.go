@@ -8,13 +8,16 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"newshub/config"
+	"newshub/crawler"
 	"newshub/models"
+	"newshub/services"
 )
 
 const PYTHON_CRAWLER_URL = "http://localhost:8001"
@@ -31,9 +34,14 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 
 	// 解析请求数据
 	var triggerReq struct {
-		Platform   string `json:"platform"`
-		CreatorURL string `json:"creator_url"`
-		Limit      int    `json:"limit"`
+		Platform   string            `json:"platform"`
+		CreatorURL string            `json:"creator_url"`
+		Limit      int               `json:"limit"`
+		Timeout    int               `json:"timeout"` // 超时时间（秒），不传时使用CRAWLER_TASK_TIMEOUT
+		Headers    map[string]string `json:"headers"` // 爬取时附加的请求头，转发给Python服务
+		// 冷却时间（分钟），不传时使用CRAWLER_TASK_COOLDOWN；force为true时忽略冷却
+		CooldownMinutes *int `json:"cooldown_minutes"`
+		Force           bool `json:"force"`
 	}
 
 	if err := c.ShouldBindJSON(&triggerReq); err != nil {
@@ -42,7 +50,14 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 		return
 	}
 
-	// 设置默认值
+	headers, err := crawler.ValidateCustomHeaders(triggerReq.Headers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置默认值，平台名称统一转换为规范标识
+	triggerReq.Platform = config.NormalizePlatform(triggerReq.Platform)
 	if triggerReq.Platform == "" {
 		triggerReq.Platform = "weibo"
 	}
@@ -50,7 +65,7 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 		// 根据平台设置合适的默认值
 		switch triggerReq.Platform {
 		case "weibo":
-			triggerReq.CreatorURL = "周杰伦中文网JayCn"  // 使用知名用户名作为默认值
+			triggerReq.CreatorURL = "周杰伦中文网JayCn" // 使用知名用户名作为默认值
 		case "bilibili":
 			triggerReq.CreatorURL = "热门视频"
 		case "douyin":
@@ -86,23 +101,31 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 	if existingTaskCount > 0 {
 		log.Printf("检测到重复任务: platform=%s, creator_url=%s", triggerReq.Platform, triggerReq.CreatorURL)
 		c.JSON(http.StatusConflict, gin.H{
-			"error":   "任务已存在",
-			"message": "相同的爬取任务正在进行中，请稍后再试",
-			"platform": triggerReq.Platform,
+			"error":       "任务已存在",
+			"message":     "相同的爬取任务正在进行中，请稍后再试",
+			"platform":    triggerReq.Platform,
 			"creator_url": triggerReq.CreatorURL,
 		})
 		return
 	}
 
+	// 与CreateCrawlerTask共用冷却检查，避免通过触发接口绕过冷却
+	if !triggerReq.Force && rejectTaskInCooldown(c, ctx, db, triggerReq.Platform, triggerReq.CreatorURL, triggerReq.CooldownMinutes) {
+		return
+	}
+
 	// 创建爬取任务记录
+	deadline := config.CrawlerTaskDeadline(time.Now(), triggerReq.Timeout)
 	task := models.CrawlerTask{
-		ID:         primitive.NewObjectID(),
-		Platform:   triggerReq.Platform,
-		CreatorURL: triggerReq.CreatorURL,
-		Limit:      triggerReq.Limit,
-		Status:     "pending",
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:          primitive.NewObjectID(),
+		Platform:    triggerReq.Platform,
+		CreatorURL:  triggerReq.CreatorURL,
+		Limit:       triggerReq.Limit,
+		Status:      "pending",
+		Deadline:    &deadline,
+		HeaderNames: crawler.CustomHeaderNames(headers),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	// 保存任务到数据库
@@ -124,6 +147,9 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 		"platform":    triggerReq.Platform,
 		"limit":       triggerReq.Limit,
 	}
+	if len(headers) > 0 {
+		platformRequest["headers"] = headers
+	}
 
 	requestBody, err := json.Marshal(platformRequest)
 	if err != nil {
@@ -145,6 +171,16 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "NewsHub-Backend/1.0")
 
+	// 全局限制同时发往Python服务的请求数，超出时排队等待
+	release, err := services.AcquirePythonDispatch(c.Request.Context())
+	if err != nil {
+		log.Printf("等待爬虫调度名额时请求已取消: %v", err)
+		updateTaskStatus(task.ID, "failed", "等待爬虫调度名额时请求已取消")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "爬虫服务繁忙，请稍后重试"})
+		return
+	}
+	defer release()
+
 	client := &http.Client{Timeout: 30 * time.Second}
 	log.Printf("转发请求到Python服务: %s", req.URL.String())
 
@@ -152,6 +188,7 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 	if err != nil {
 		log.Printf("Python爬虫服务请求失败: %v", err)
 		updateTaskStatus(task.ID, "failed", "Python爬虫服务不可用: "+err.Error())
+		services.RecordCrawlOutcome(task.Platform, services.CrawlOutcomeError)
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error":   "Python爬虫服务不可用",
 			"details": err.Error(),
@@ -161,6 +198,7 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
+	release()
 	if err != nil {
 		log.Printf("读取Python服务响应失败: %v", err)
 		updateTaskStatus(task.ID, "failed", "读取Python服务响应失败")
@@ -169,8 +207,12 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 	}
 
 	log.Printf("Python服务响应状态: %d", resp.StatusCode)
+	saveTaskRawResult(task.ID, resp.StatusCode, respBody)
 
 	// 处理响应
+	status := "failed"
+	outcome := ""
+	fetchedCount, savedCount, fallbackCount := 0, 0, 0
 	if resp.StatusCode == http.StatusOK {
 		// 解析爬取结果
 		var crawlResult map[string]interface{}
@@ -187,18 +229,22 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 				// 如果有total字段且大于0，尝试直接使用结果
 				posts = []interface{}{crawlResult}
 			}
+			fetchedCount = len(posts)
+			fallbackCount = countFallbackItems(posts)
 
 			if len(posts) > 0 {
-				if err := SaveCrawlerContent(task.ID, posts); err != nil {
+				summary, err := SaveCrawlerContent(task.ID, posts)
+				if err != nil {
 					log.Printf("保存爬取内容失败: %v", err)
 					updateTaskStatus(task.ID, "failed", "保存爬取内容失败")
 				} else {
-					log.Printf("成功保存 %d 条爬取内容", len(posts))
-					updateTaskStatus(task.ID, "completed", "")
+					log.Printf("成功保存 %d 条爬取内容（重复 %d，失败 %d）", summary.Saved, summary.Duplicates, summary.Errors)
+					status = "completed"
+					savedCount = summary.Saved
 				}
 			} else {
 				log.Printf("未找到有效的爬取内容，但任务完成")
-				updateTaskStatus(task.ID, "completed", "")
+				status = "completed"
 			}
 		} else {
 			log.Printf("解析爬取结果失败: %v", err)
@@ -210,11 +256,24 @@ func ProxyCrawlerTrigger(c *gin.Context) {
 		updateTaskStatus(task.ID, "failed", errorMsg)
 	}
 
+	// 区分有新内容的完成与空结果的完成，便于前端提示"没有新内容"
+	if status == "completed" {
+		outcome = "completed"
+		if savedCount == 0 {
+			outcome = "completed_empty"
+		}
+		updateTaskResult(task.ID, outcome, fetchedCount, savedCount)
+	}
+	services.RecordCrawlOutcome(task.Platform, services.ClassifyCrawlOutcome(status == "completed", fetchedCount, fallbackCount))
+
 	// 返回任务信息和爬取结果
 	result := map[string]interface{}{
-		"task_id": task.ID.Hex(),
-		"status":  task.Status,
-		"message": "爬取任务已创建并执行",
+		"task_id":       task.ID.Hex(),
+		"status":        status,
+		"outcome":       outcome,
+		"fetched_count": fetchedCount,
+		"saved_count":   savedCount,
+		"message":       "爬取任务已创建并执行",
 	}
 
 	// 如果有爬取结果，也一并返回
@@ -266,33 +325,187 @@ func ProxyCrawlerStatus(c *gin.Context) {
 }
 
 // GetCrawlerPlatforms 获取支持的爬虫平台列表
+// 合并Python服务与Go内置爬虫的平台（按平台名去重，source 标记来源），Python服务不可用时只返回内置平台；
+// source=builtin 时只返回Go内置爬虫注册的平台
 func GetCrawlerPlatforms(c *gin.Context) {
 	log.Println("获取支持的爬虫平台列表")
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(PYTHON_CRAWLER_URL + "/platforms")
+	builtin := builtinPlatforms()
+	if c.Query("source") == "builtin" {
+		c.JSON(http.StatusOK, gin.H{"platforms": builtin})
+		return
+	}
+
+	pythonPlatforms, err := fetchPythonPlatforms()
 	if err != nil {
-		log.Printf("获取平台列表失败: %v", err)
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "Python爬虫服务不可用",
-			"details": err.Error(),
+		log.Printf("获取Python服务平台列表失败，使用内置平台: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"platforms":        builtin,
+			"python_available": false,
 		})
 		return
 	}
+
+	platforms := make([]gin.H, 0, len(pythonPlatforms)+len(builtin))
+	seen := make(map[string]bool)
+	for _, platform := range append(pythonPlatforms, builtin...) {
+		key := config.NormalizePlatform(fmt.Sprint(platform["name"]))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		platforms = append(platforms, platform)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"platforms":        platforms,
+		"python_available": true,
+	})
+}
+
+// fetchPythonPlatforms 获取Python服务支持的平台，兼容 {"platforms": [...]} 与数组两种响应，
+// 元素可以是平台名字符串或带 name/id/platform 字段的对象
+func fetchPythonPlatforms() ([]gin.H, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(PYTHON_CRAWLER_URL + "/platforms")
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Python服务返回错误状态: %d", resp.StatusCode)
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("解析平台列表失败: %v", err)
+	}
+
+	items, ok := body.([]interface{})
+	if object, isObject := body.(map[string]interface{}); isObject {
+		items, ok = object["platforms"].([]interface{})
+	}
+	if !ok {
+		return nil, fmt.Errorf("平台列表格式不正确")
+	}
+
+	platforms := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		switch value := item.(type) {
+		case string:
+			platforms = append(platforms, gin.H{"name": value, "source": "python"})
+		case map[string]interface{}:
+			platform := gin.H{}
+			for k, v := range value {
+				platform[k] = v
+			}
+			if _, hasName := platform["name"]; !hasName {
+				for _, key := range []string{"id", "platform"} {
+					if name, ok := value[key].(string); ok {
+						platform["name"] = name
+						break
+					}
+				}
+			}
+			if _, hasName := platform["name"]; !hasName {
+				continue
+			}
+			platform["source"] = "python"
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// builtinPlatforms Go内置爬虫注册的平台及其搜索引擎
+func builtinPlatforms() []gin.H {
+	names := crawler.ListPlatforms()
+	platforms := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		cfg, _ := crawler.GetPlatformConfig(name)
+		engines := make([]string, 0, len(cfg.SearchEngines))
+		for _, engine := range cfg.SearchEngines {
+			engines = append(engines, engine.Name)
+		}
+		platforms = append(platforms, gin.H{
+			"name":           name,
+			"search_engines": engines,
+			"source":         "builtin",
+		})
+	}
+	return platforms
+}
+
+// countFallbackItems 统计Python服务响应中标记为备用内容（is_fallback）的条数
+func countFallbackItems(items []interface{}) int {
+	count := 0
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if fallback, _ := m["is_fallback"].(bool); fallback {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// saveTaskRawResult 保存Python服务的原始响应，便于区分上游返回不足与Go侧去重、解析丢弃
+func saveTaskRawResult(taskID primitive.ObjectID, statusCode int, body []byte) {
+	result := models.CrawlerTaskResult{
+		StatusCode: statusCode,
+		Size:       len(body),
+		ReceivedAt: time.Now(),
+	}
+	if limit := config.GetCrawlerRawResultLimit(); limit > 0 && len(body) > limit {
+		body = body[:limit]
+		result.Truncated = true
+	}
+	// 截断可能切开多字节字符
+	result.Body = strings.ToValidUTF8(string(body), "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := config.GetDB().Collection("crawler_tasks").UpdateOne(
+		ctx,
+		map[string]interface{}{"_id": taskID},
+		map[string]interface{}{"$set": map[string]interface{}{"result": result}},
+	)
 	if err != nil {
-		log.Printf("读取平台列表响应失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取响应失败"})
-		return
+		log.Printf("保存Python原始响应失败: %v", err)
 	}
+}
+
+// updateTaskResult 将任务标记为完成并记录爬取结果统计，已失败的任务保持失败状态
+func updateTaskResult(taskID primitive.ObjectID, outcome string, fetched, saved int) {
+	db := config.GetDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// 转发响应
-	c.Header("Content-Type", "application/json")
-	c.Status(resp.StatusCode)
-	c.Writer.Write(respBody)
+	// 已被标记为失败的任务（如超过截止时间）不再改回完成
+	now := time.Now()
+	result, err := db.Collection("crawler_tasks").UpdateOne(
+		ctx,
+		map[string]interface{}{"_id": taskID, "status": map[string]interface{}{"$ne": "failed"}},
+		map[string]interface{}{"$set": map[string]interface{}{
+			"status":        "completed",
+			"outcome":       outcome,
+			"fetched_count": fetched,
+			"saved_count":   saved,
+			"completed_at":  now,
+			"updated_at":    now,
+		}},
+	)
+
+	switch {
+	case err != nil:
+		log.Printf("更新任务结果失败: %v", err)
+	case result.MatchedCount == 0:
+		log.Printf("任务已失败或不存在，不更新结果: %s (获取=%d, 保存=%d)", taskID.Hex(), fetched, saved)
+	default:
+		log.Printf("任务结果更新成功: %s -> %s (获取=%d, 保存=%d)", taskID.Hex(), outcome, fetched, saved)
+	}
 }
 
 // updateTaskStatus 更新任务状态的辅助函数
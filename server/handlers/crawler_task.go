@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,15 +20,23 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"newshub/config"
+	"newshub/crawler"
 	"newshub/models"
+	"newshub/services"
+	"newshub/utils"
 )
 
 // CreateCrawlerTask 创建爬取任务
 func CreateCrawlerTask(c *gin.Context) {
 	var req struct {
-		Platform   string `json:"platform" binding:"required"`
-		CreatorURL string `json:"creator_url" binding:"required"`
-		Limit      int    `json:"limit"`
+		Platform   string            `json:"platform" binding:"required"`
+		CreatorURL string            `json:"creator_url" binding:"required"`
+		Limit      int               `json:"limit"`
+		Timeout    int               `json:"timeout"` // 超时时间（秒），不传时使用CRAWLER_TASK_TIMEOUT
+		Headers    map[string]string `json:"headers"` // 爬取时附加的请求头
+		// 冷却时间（分钟），不传时使用CRAWLER_TASK_COOLDOWN；force为true时忽略冷却
+		CooldownMinutes *int `json:"cooldown_minutes"`
+		Force           bool `json:"force"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -33,25 +44,39 @@ func CreateCrawlerTask(c *gin.Context) {
 		return
 	}
 
-	if req.Limit <= 0 {
-		req.Limit = 10
+	headers, err := crawler.ValidateCustomHeaders(req.Headers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	task := models.CrawlerTask{
-		ID:         primitive.NewObjectID(),
-		Platform:   req.Platform,
-		CreatorURL: req.CreatorURL,
-		Limit:      req.Limit,
-		Status:     "pending",
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	if req.Limit <= 0 {
+		req.Limit = 10
 	}
+	req.Platform = config.NormalizePlatform(req.Platform)
 
 	db := config.GetDB()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := db.Collection("crawler_tasks").InsertOne(ctx, task)
+	if !req.Force && rejectTaskInCooldown(c, ctx, db, req.Platform, req.CreatorURL, req.CooldownMinutes) {
+		return
+	}
+
+	deadline := config.CrawlerTaskDeadline(time.Now(), req.Timeout)
+	task := models.CrawlerTask{
+		ID:          primitive.NewObjectID(),
+		Platform:    req.Platform,
+		CreatorURL:  req.CreatorURL,
+		Limit:       req.Limit,
+		Status:      "pending",
+		Deadline:    &deadline,
+		HeaderNames: crawler.CustomHeaderNames(headers),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	_, err = db.Collection("crawler_tasks").InsertOne(ctx, task)
 	if err != nil {
 		log.Printf("创建爬取任务失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建爬取任务失败"})
@@ -62,16 +87,84 @@ func CreateCrawlerTask(c *gin.Context) {
 	c.JSON(http.StatusCreated, task)
 }
 
+// rejectTaskInCooldown 相同平台和创作者的任务在冷却期内已完成时返回409并返回true
+// cooldownMinutes 为空时使用 CRAWLER_TASK_COOLDOWN，小于等于0表示不限制
+func rejectTaskInCooldown(c *gin.Context, ctx context.Context, db *mongo.Database, platform, creatorURL string, cooldownMinutes *int) bool {
+	cooldown := config.GetCrawlerTaskCooldown()
+	if cooldownMinutes != nil {
+		cooldown = time.Duration(*cooldownMinutes) * time.Minute
+	}
+	if cooldown <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	var last models.CrawlerTask
+	err := db.Collection("crawler_tasks").FindOne(ctx, bson.M{
+		"platform":     platform,
+		"creator_url":  creatorURL,
+		"status":       "completed",
+		"completed_at": bson.M{"$gt": now.Add(-cooldown)},
+	}, options.FindOne().SetSort(bson.D{{Key: "completed_at", Value: -1}})).Decode(&last)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			// 冷却检查失败不阻止创建任务
+			log.Printf("检查任务冷却失败: %v", err)
+		}
+		return false
+	}
+
+	retryAfter := last.CompletedAt.Add(cooldown).Sub(now)
+	log.Printf("任务仍在冷却期: platform=%s, creator_url=%s, 剩余%v", platform, creatorURL, retryAfter)
+	c.JSON(http.StatusConflict, gin.H{
+		"error":               "任务冷却中",
+		"message":             "相同的爬取任务刚刚完成，请稍后再试或使用force强制执行",
+		"platform":            platform,
+		"creator_url":         creatorURL,
+		"last_task_id":        last.ID.Hex(),
+		"retry_after_seconds": int(math.Ceil(retryAfter.Seconds())),
+	})
+	return true
+}
+
 // GetCrawlerTasks 获取爬取任务列表
+// 支持 platform、status、from/to（RFC3339，按创建时间）过滤，以及 limit/page 分页
 func GetCrawlerTasks(c *gin.Context) {
+	filter := bson.M{}
+	if platform := config.NormalizePlatform(c.Query("platform")); platform != "" {
+		filter["platform"] = platform
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	createdAt, err := parseCreatedAtRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if createdAt != nil {
+		filter["created_at"] = createdAt
+	}
+
 	db := config.GetDB()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	total, err := db.Collection("crawler_tasks").CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("统计爬取任务数量失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取爬取任务列表失败"})
+		return
+	}
+
 	// 构建查询选项，按创建时间倒序排列
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(50)
+	// 列表不返回体积较大的原始响应
+	pagination := parsePagination(c, "tasks")
+	opts := pagination.Apply(options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"result": 0}))
 
-	cursor, err := db.Collection("crawler_tasks").Find(ctx, bson.M{}, opts)
+	cursor, err := db.Collection("crawler_tasks").Find(ctx, filter, opts)
 	if err != nil {
 		log.Printf("获取爬取任务列表失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取爬取任务列表失败"})
@@ -93,7 +186,125 @@ func GetCrawlerTasks(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"tasks": tasks,
-		"total": len(tasks),
+		"total": total,
+		"page":  pagination.Page,
+		"limit": pagination.Limit,
+	})
+}
+
+// parseCreatedAtRange 解析 from/to 查询参数（RFC3339），返回 created_at 的范围条件，均未传时返回nil
+func parseCreatedAtRange(c *gin.Context) (bson.M, error) {
+	createdAt := bson.M{}
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return nil, errors.New("from 必须是RFC3339格式的时间")
+		}
+		createdAt["$gte"] = from
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return nil, errors.New("to 必须是RFC3339格式的时间")
+		}
+		createdAt["$lte"] = to
+	}
+	if len(createdAt) == 0 {
+		return nil, nil
+	}
+	return createdAt, nil
+}
+
+// GetCrawlerTaskStats 按状态和平台统计爬取任务数量，以及各平台的平均完成耗时
+// 可选参数 from/to（RFC3339）按任务创建时间过滤
+func GetCrawlerTaskStats(c *gin.Context) {
+	match := bson.M{}
+	createdAt, err := parseCreatedAtRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if createdAt != nil {
+		match["created_at"] = createdAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 只有记录了开始和完成时间的已完成任务参与平均耗时计算，$avg 会忽略 null
+	completedDuration := bson.M{"$cond": bson.A{
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$status", "completed"}},
+			bson.M{"$gt": bson.A{"$started_at", nil}},
+			bson.M{"$gt": bson.A{"$completed_at", nil}},
+		}},
+		bson.M{"$subtract": bson.A{"$completed_at", "$started_at"}},
+		nil,
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"by_status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"count": -1}},
+			},
+			"by_platform": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":             "$platform",
+					"count":           bson.M{"$sum": 1},
+					"avg_duration_ms": bson.M{"$avg": completedDuration},
+				}},
+				bson.M{"$sort": bson.M{"count": -1}},
+			},
+		}}},
+	}
+
+	cursor, err := config.GetDB().Collection("crawler_tasks").Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("统计爬取任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计爬取任务失败"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ByStatus []struct {
+			Status string `bson:"_id"`
+			Count  int64  `bson:"count"`
+		} `bson:"by_status"`
+		ByPlatform []struct {
+			Platform      string   `bson:"_id"`
+			Count         int64    `bson:"count"`
+			AvgDurationMs *float64 `bson:"avg_duration_ms"`
+		} `bson:"by_platform"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Printf("解析任务统计失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析任务统计失败"})
+		return
+	}
+
+	var total int64
+	byStatus := gin.H{}
+	byPlatform := []gin.H{}
+	if len(results) > 0 {
+		for _, item := range results[0].ByStatus {
+			byStatus[item.Status] = item.Count
+			total += item.Count
+		}
+		for _, item := range results[0].ByPlatform {
+			entry := gin.H{"platform": item.Platform, "count": item.Count, "avg_complete_seconds": nil}
+			if item.AvgDurationMs != nil {
+				entry["avg_complete_seconds"] = *item.AvgDurationMs / 1000
+			}
+			byPlatform = append(byPlatform, entry)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"by_status":   byStatus,
+		"by_platform": byPlatform,
 	})
 }
 
@@ -121,6 +332,37 @@ func GetCrawlerTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
+// GetCrawlerTaskRaw 获取爬取任务保存的Python服务原始响应
+func GetCrawlerTaskRaw(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var task models.CrawlerTask
+	opts := options.FindOne().SetProjection(bson.M{"result": 1, "fetched_count": 1, "saved_count": 1})
+	err = config.GetDB().Collection("crawler_tasks").FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&task)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if task.Result == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该任务没有保存原始响应"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":       task.ID.Hex(),
+		"fetched_count": task.FetchedCount,
+		"saved_count":   task.SavedCount,
+		"result":        task.Result,
+	})
+}
+
 // UpdateCrawlerTaskStatus 更新爬取任务状态
 func UpdateCrawlerTaskStatus(c *gin.Context) {
 	taskID := c.Param("id")
@@ -131,14 +373,24 @@ func UpdateCrawlerTaskStatus(c *gin.Context) {
 	}
 
 	var req struct {
-		Status string `json:"status" binding:"required"`
-		Error  string `json:"error,omitempty"`
+		Status         string `json:"status" binding:"required"`
+		Error          string `json:"error,omitempty"`
+		Progress       *int   `json:"progress,omitempty"`        // 0-100
+		ItemsCollected *int   `json:"items_collected,omitempty"` // 已采集的条数
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Progress != nil && (*req.Progress < 0 || *req.Progress > 100) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "progress 必须在0到100之间"})
+		return
+	}
+	if req.ItemsCollected != nil && *req.ItemsCollected < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items_collected 不能为负数"})
+		return
+	}
 
 	db := config.GetDB()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -152,6 +404,12 @@ func UpdateCrawlerTaskStatus(c *gin.Context) {
 	if req.Error != "" {
 		update["error"] = req.Error
 	}
+	if req.Progress != nil {
+		update["progress"] = *req.Progress
+	}
+	if req.ItemsCollected != nil {
+		update["items_collected"] = *req.ItemsCollected
+	}
 
 	// 根据状态设置时间字段
 	now := time.Now()
@@ -160,6 +418,10 @@ func UpdateCrawlerTaskStatus(c *gin.Context) {
 		update["started_at"] = now
 	case "completed", "failed":
 		update["completed_at"] = now
+		// 完成时未上报进度则视为100%
+		if req.Status == "completed" && req.Progress == nil {
+			update["progress"] = 100
+		}
 	}
 
 	_, err = db.Collection("crawler_tasks").UpdateOne(
@@ -191,12 +453,15 @@ func DeleteCrawlerTask(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// 删除相关的爬取内容
-	_, err = db.Collection("crawler_contents").DeleteMany(ctx, bson.M{"task_id": objectID})
+	// 删除相关的爬取内容及其物化帖子
+	_, err = deleteContentsMatching(ctx, db, bson.M{"task_id": objectID})
 	if err != nil {
 		log.Printf("删除爬取内容失败: %v", err)
 		// 继续删除任务，即使内容删除失败
 	}
+	if _, err := db.Collection("crawler_content_full").DeleteMany(ctx, bson.M{"task_id": objectID}); err != nil {
+		log.Printf("删除完整文本失败: %v", err)
+	}
 
 	// 删除爬取任务
 	result, err := db.Collection("crawler_tasks").DeleteOne(ctx, bson.M{"_id": objectID})
@@ -250,7 +515,7 @@ func BatchDeleteCrawlerTasks(c *gin.Context) {
 		// 使用过滤条件
 		filter = bson.M{}
 		if req.Filter.Platform != "" {
-			filter["platform"] = req.Filter.Platform
+			filter["platform"] = config.NormalizePlatform(req.Filter.Platform)
 		}
 		if req.Filter.CreatorURL != "" {
 			filter["creator_url"] = req.Filter.CreatorURL
@@ -282,11 +547,14 @@ func BatchDeleteCrawlerTasks(c *gin.Context) {
 		return
 	}
 
-	// 删除相关的爬取内容
-	contentResult, err := db.Collection("crawler_contents").DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": taskIDs}})
+	// 删除相关的爬取内容及其物化帖子
+	deletedContents, err := deleteContentsMatching(ctx, db, bson.M{"task_id": bson.M{"$in": taskIDs}})
 	if err != nil {
 		log.Printf("批量删除爬取内容失败: %v", err)
 	}
+	if _, err := db.Collection("crawler_content_full").DeleteMany(ctx, bson.M{"task_id": bson.M{"$in": taskIDs}}); err != nil {
+		log.Printf("批量删除完整文本失败: %v", err)
+	}
 
 	// 删除爬取任务
 	taskResult, err := db.Collection("crawler_tasks").DeleteMany(ctx, filter)
@@ -296,11 +564,11 @@ func BatchDeleteCrawlerTasks(c *gin.Context) {
 		return
 	}
 
-	log.Printf("批量删除完成: 删除了 %d 个任务和 %d 条内容", taskResult.DeletedCount, contentResult.DeletedCount)
+	log.Printf("批量删除完成: 删除了 %d 个任务和 %d 条内容", taskResult.DeletedCount, deletedContents)
 	c.JSON(http.StatusOK, gin.H{
-		"message":              "批量删除成功",
+		"message":               "批量删除成功",
 		"deleted_tasks_count":   taskResult.DeletedCount,
-		"deleted_content_count": contentResult.DeletedCount,
+		"deleted_content_count": deletedContents,
 	})
 }
 
@@ -321,11 +589,36 @@ func GetCrawlerContents(c *gin.Context) {
 		}
 		filter["task_id"] = objectID
 	}
+	if platform := c.Query("platform"); platform != "" {
+		filter["platform"] = config.NormalizePlatform(platform)
+	}
+
+	// total 统计整个过滤条件下的数量，不受分页游标影响
+	total, err := db.Collection("crawler_contents").CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("统计爬取内容数量失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取爬取内容列表失败"})
+		return
+	}
 
-	// 按创建时间倒序排列
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+	pagination := parsePagination(c, "contents")
 
-	cursor, err := db.Collection("crawler_contents").Find(ctx, filter, opts)
+	// before 游标：上一页最后一条的ID，或RFC3339时间
+	query := filter
+	if before := c.Query("before"); before != "" {
+		cursorFilter, err := contentsBeforeFilter(ctx, db, before)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = bson.M{"$and": bson.A{filter, cursorFilter}}
+		pagination.Skip = 0
+	}
+
+	// 按创建时间倒序排列，_id 用于区分同一时间的记录
+	opts := pagination.Apply(options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}))
+
+	cursor, err := db.Collection("crawler_contents").Find(ctx, query, opts)
 	if err != nil {
 		log.Printf("获取爬取内容列表失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取爬取内容列表失败"})
@@ -345,51 +638,557 @@ func GetCrawlerContents(c *gin.Context) {
 		contents = []models.CrawlerContent{}
 	}
 
+	// 返回满一页时提供下一页游标
+	nextCursor := ""
+	if pagination.Limit > 0 && int64(len(contents)) == pagination.Limit {
+		nextCursor = contents[len(contents)-1].ID.Hex()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contents":    contents,
+		"total":       total,
+		"next_cursor": nextCursor,
+	})
+}
+
+// DeleteCrawlerContents 按条件批量删除爬取内容
+// 支持 platform、task_id、before（RFC3339，删除该时间之前入库的内容）与 ids，条件之间为且的关系；
+// 不带任何条件时需显式传 all: true 才会清空全部内容；完整文本与物化的帖子一并删除
+func DeleteCrawlerContents(c *gin.Context) {
+	var req struct {
+		Platform string     `json:"platform"`
+		TaskID   string     `json:"task_id"`
+		Before   *time.Time `json:"before"`
+		IDs      []string   `json:"ids"`
+		All      bool       `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if req.Platform != "" {
+		filter["platform"] = config.NormalizePlatform(req.Platform)
+	}
+	if req.TaskID != "" {
+		taskID, err := primitive.ObjectIDFromHex(req.TaskID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+			return
+		}
+		filter["task_id"] = taskID
+	}
+	if req.Before != nil {
+		filter["created_at"] = bson.M{"$lt": *req.Before}
+	}
+	if len(req.IDs) > 0 {
+		objectIDs := make([]primitive.ObjectID, 0, len(req.IDs))
+		for _, idStr := range req.IDs {
+			objectID, err := primitive.ObjectIDFromHex(idStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容ID: " + idStr})
+				return
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+		filter["_id"] = bson.M{"$in": objectIDs}
+	}
+	if len(filter) == 0 && !req.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未指定删除条件，如需删除全部内容请传 all: true"})
+		return
+	}
+
+	db := config.GetDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	deleted, err := deleteContentsMatching(ctx, db, filter)
+	if err != nil {
+		log.Printf("批量删除爬取内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量删除爬取内容失败", "deleted_count": deleted})
+		return
+	}
+
+	log.Printf("按条件删除爬取内容完成: 删除了 %d 条内容", deleted)
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "批量删除成功",
+		"deleted_count": deleted,
+	})
+}
+
+// deleteContentsMatching 先收集匹配的内容ID，再分批连同完整文本与物化帖子一起删除，返回删除的内容数
+func deleteContentsMatching(ctx context.Context, db *mongo.Database, filter bson.M) (int64, error) {
+	cursor, err := db.Collection("crawler_contents").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	const batchSize = 1000
+	var (
+		deleted int64
+		batch   []primitive.ObjectID
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := deleteContentsByID(ctx, db, batch)
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		batch = append(batch, doc.ID)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
+// contentsBeforeFilter 将before游标转换为查询条件
+// 游标为内容ID时返回排在该内容之后的记录，为时间时返回早于该时间的记录
+func contentsBeforeFilter(ctx context.Context, db *mongo.Database, before string) (bson.M, error) {
+	if objectID, err := primitive.ObjectIDFromHex(before); err == nil {
+		var anchor struct {
+			CreatedAt time.Time `bson:"created_at"`
+		}
+		opts := options.FindOne().SetProjection(bson.M{"created_at": 1})
+		if err := db.Collection("crawler_contents").FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&anchor); err != nil {
+			return nil, fmt.Errorf("无效的游标: 内容不存在")
+		}
+		return bson.M{"$or": bson.A{
+			bson.M{"created_at": bson.M{"$lt": anchor.CreatedAt}},
+			bson.M{"created_at": anchor.CreatedAt, "_id": bson.M{"$lt": objectID}},
+		}}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, before)
+	if err != nil {
+		return nil, fmt.Errorf("无效的游标: 需要内容ID或RFC3339时间")
+	}
+	return bson.M{"created_at": bson.M{"$lt": t}}, nil
+}
+
+// StreamCrawlerContents 以NDJSON流式导出爬取内容，按_id升序输出，可通过游标断点续传
+// 最后一行为 {"cursor": "...", "count": n}，下次请求以 after=<cursor> 继续
+func StreamCrawlerContents(c *gin.Context) {
+	filter := bson.M{}
+	after := c.Query("after")
+	if after != "" {
+		afterID, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的游标"})
+			return
+		}
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	if platform := c.Query("platform"); platform != "" {
+		filter["platform"] = config.NormalizePlatform(platform)
+	}
+	if taskID := c.Query("task_id"); taskID != "" {
+		objectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+			return
+		}
+		filter["task_id"] = objectID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(500)
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.ParseInt(limitStr, 10, 64); err == nil && limit > 0 {
+			opts.SetLimit(limit)
+		}
+	}
+
+	// 使用请求上下文，客户端断开时停止读取
+	ctx := c.Request.Context()
+	cursor, err := config.GetDB().Collection("crawler_contents").Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("导出爬取内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出爬取内容失败"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	lastCursor := after
+	count := 0
+	for cursor.Next(ctx) {
+		var content models.CrawlerContent
+		if err := cursor.Decode(&content); err != nil {
+			log.Printf("解析爬取内容失败: %v", err)
+			continue
+		}
+		if err := encoder.Encode(content); err != nil {
+			log.Printf("写入导出流失败: %v", err)
+			return
+		}
+		lastCursor = content.ID.Hex()
+		count++
+		if count%100 == 0 {
+			c.Writer.Flush()
+		}
+	}
+
+	result := gin.H{"cursor": lastCursor, "count": count}
+	if err := cursor.Err(); err != nil {
+		log.Printf("导出爬取内容中断: %v", err)
+		result["error"] = "导出中断，请使用cursor继续"
+	}
+	encoder.Encode(result)
+	c.Writer.Flush()
+}
+
+// SimilarContent 相似内容结果
+type SimilarContent struct {
+	models.CrawlerContent
+	Distance   int     `json:"distance"`   // 与目标内容的海明距离
+	Similarity float64 `json:"similarity"` // 相似度（1 - 距离/64）
+}
+
+// maxSimilarContentsLimit 相似内容接口单次最多返回的条数
+const maxSimilarContentsLimit = 100
+
+// GetSimilarContents 获取与指定内容相似的其他内容（基于SimHash分段索引），limit 默认20，最多100
+func GetSimilarContents(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容ID"})
+		return
+	}
+
+	// 分段索引只能保证召回海明距离不超过 SimHashBandCount-1 的内容
+	maxDistance := utils.SimHashBandCount - 1
+	if distanceStr := c.Query("max_distance"); distanceStr != "" {
+		parsed, err := strconv.Atoi(distanceStr)
+		if err != nil || parsed < 0 || parsed > utils.SimHashBandCount-1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("max_distance 必须在 0 到 %d 之间", utils.SimHashBandCount-1)})
+			return
+		}
+		maxDistance = parsed
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxSimilarContentsLimit {
+		limit = maxSimilarContentsLimit
+	}
+
+	db := config.GetDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var target models.CrawlerContent
+	if err := db.Collection("crawler_contents").FindOne(ctx, bson.M{"_id": objectID}).Decode(&target); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "内容不存在"})
+			return
+		}
+		log.Printf("获取爬取内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取爬取内容失败"})
+		return
+	}
+
+	// 历史数据可能没有存储指纹，此时现场计算
+	fingerprint, err := utils.ParseSimHash(target.SimHash)
+	if err != nil {
+		fingerprint = utils.SimHash(target.Title + " " + target.Content)
+	}
+
+	filter := bson.M{
+		"_id":           bson.M{"$ne": objectID},
+		"simhash_bands": bson.M{"$in": utils.SimHashBands(fingerprint)},
+	}
+	// 限制候选集大小，避免热门band导致大量读取
+	opts := options.Find().SetLimit(1000)
+
+	cursor, err := db.Collection("crawler_contents").Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("查询相似内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询相似内容失败"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.CrawlerContent
+	if err := cursor.All(ctx, &candidates); err != nil {
+		log.Printf("解析相似内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析相似内容失败"})
+		return
+	}
+
+	similar := []SimilarContent{}
+	for _, candidate := range candidates {
+		candidateHash, err := utils.ParseSimHash(candidate.SimHash)
+		if err != nil {
+			continue
+		}
+		distance := utils.HammingDistance(fingerprint, candidateHash)
+		if distance > maxDistance {
+			continue
+		}
+		similar = append(similar, SimilarContent{
+			CrawlerContent: candidate,
+			Distance:       distance,
+			Similarity:     1 - float64(distance)/64,
+		})
+	}
+
+	// 按相似度从高到低排序，距离相同时较新的内容优先
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Distance != similar[j].Distance {
+			return similar[i].Distance < similar[j].Distance
+		}
+		return similar[i].CreatedAt.After(similar[j].CreatedAt)
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"content_id":   target.ID.Hex(),
+		"simhash":      utils.FormatSimHash(fingerprint),
+		"max_distance": maxDistance,
+		"contents":     similar,
+		"total":        len(similar),
+	})
+}
+
+// GetCrawlerContentFull 获取爬取内容的完整文本，未被截断的内容直接返回原文
+func GetCrawlerContentFull(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容ID"})
+		return
+	}
+
+	db := config.GetDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var content models.CrawlerContent
+	if err := db.Collection("crawler_contents").FindOne(ctx, bson.M{"_id": id}).Decode(&content); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "内容不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取内容失败"})
+		return
+	}
+
+	if content.Truncated {
+		var full models.CrawlerContentFull
+		err := db.Collection("crawler_content_full").FindOne(ctx, bson.M{"_id": id}).Decode(&full)
+		if err == nil {
+			content.Title = full.Title
+			content.Content = full.Content
+			content.Truncated = false
+		} else if err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取完整文本失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           content,
+		"full_available": !content.Truncated,
+	})
+}
+
+// TestCrawlResult 测试爬取的单条结果，附带去重判定
+type TestCrawlResult struct {
+	models.CrawlerContent
+	Duplicate     bool   `json:"duplicate"`
+	DuplicateType string `json:"duplicate_type,omitempty"`
+	DuplicateOf   string `json:"duplicate_of,omitempty"` // 近似重复时命中的已入库内容ID
+}
+
+// TestCrawl 使用Go内置爬虫（搜索引擎方式）执行一次爬取，不经过Python服务且不保存结果
+func TestCrawl(c *gin.Context) {
+	var req struct {
+		Platform string            `json:"platform" binding:"required"`
+		Query    string            `json:"query" binding:"required"`
+		Limit    int               `json:"limit"`
+		Headers  map[string]string `json:"headers"` // 爬取时附加的请求头
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	headers, err := crawler.ValidateCustomHeaders(req.Headers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Limit > 50 {
+		req.Limit = 50
+	}
+	platform := config.NormalizePlatform(req.Platform)
+
+	start := time.Now()
+	contents, err := crawler.CrawlPlatformContentRaw(c.Request.Context(), platform, req.Query, req.Limit, headers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	elapsed := time.Since(start)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 按与SaveCrawlerContent相同的规则计算哈希并判断是否重复（不计入去重统计）
+	dedup := services.GetDeduplicationService()
+	dedupSince, err := dedup.WindowStart(ctx)
+	if err != nil {
+		log.Printf("计算去重范围失败，使用全量比对: %v", err)
+	}
+	results := make([]TestCrawlResult, 0, len(contents))
+	for _, content := range contents {
+		content.ContentHash = utils.TitleContentHash(content.Title, content.Content)
+		simHash := utils.SimHash(content.Title + " " + content.Content)
+		content.SimHash = utils.FormatSimHash(simHash)
+		storedTitle, _ := utils.TruncateRunes(content.Title, config.GetContentLimits().MaxTitleLength)
+
+		duplicate, err := dedup.IsDuplicate(ctx, services.DuplicateCheck{
+			ContentHash: content.ContentHash,
+			Platform:    content.Platform,
+			Author:      content.Author,
+			Title:       storedTitle,
+			URL:         content.URL,
+			SimHash:     simHash,
+			Since:       dedupSince,
+		})
+		if err != nil {
+			log.Printf("检查内容重复失败: %v", err)
+		}
+		result := TestCrawlResult{
+			CrawlerContent: content,
+			Duplicate:      duplicate.Duplicate,
+			DuplicateType:  duplicate.Type,
+		}
+		if !duplicate.ExistingID.IsZero() {
+			result.DuplicateOf = duplicate.ExistingID.Hex()
+		}
+		results = append(results, result)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"contents": contents,
-		"total":    len(contents),
+		"platform":    platform,
+		"query":       req.Query,
+		"count":       len(results),
+		"duration_ms": elapsed.Milliseconds(),
+		"data":        results,
 	})
 }
 
+// SaveSummary 一次保存爬取内容的统计
+type SaveSummary struct {
+	Total      int `json:"total"`      // 传入的条数
+	Saved      int `json:"saved"`      // 实际保存的条数
+	Duplicates int `json:"duplicates"` // 去重跳过或被唯一索引拒绝的条数
+	Errors     int `json:"errors"`     // 解析或写入失败的条数
+}
+
 // SaveCrawlerContent 保存爬取内容
-func SaveCrawlerContent(taskID primitive.ObjectID, posts []interface{}) error {
+// 先按去重规则过滤，再以 ordered:false 批量写入；content_hash唯一索引是最终的去重保证，
+// 被索引拒绝的条目计为重复而不会使整批失败
+func SaveCrawlerContent(taskID primitive.ObjectID, posts []interface{}) (SaveSummary, error) {
+	summary := SaveSummary{Total: len(posts)}
 	if len(posts) == 0 {
-		return nil
+		return summary, nil
 	}
 
 	db := config.GetDB()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
 	var contents []interface{}
-	duplicateCount := 0
+	var fullTexts []interface{}
+	batchHashes := make(map[string]bool)
+	limits := config.GetContentLimits()
+
+	dedup := services.GetDeduplicationService()
+	dedupSince, err := dedup.WindowStart(ctx)
+	if err != nil {
+		log.Printf("计算去重范围失败，使用全量比对: %v", err)
+	}
 
 	for _, post := range posts {
 		postMap, ok := post.(map[string]interface{})
 		if !ok {
+			summary.Errors++
 			continue
 		}
 
 		// 生成内容哈希
 		contentText := getStringValue(postMap, "content")
 		title := getStringValue(postMap, "title")
-		combinedContent := title + "|" + contentText
-		contentHash := generateContentHash(combinedContent)
+		contentHash := utils.TitleContentHash(title, contentText)
+
+		// 同一批次内的重复内容无需查库
+		if batchHashes[contentHash] {
+			summary.Duplicates++
+			continue
+		}
 
 		// 检查内容是否已存在（基于哈希）
-		platform := getStringValue(postMap, "platform")
+		platform := config.NormalizePlatform(getStringValue(postMap, "platform"))
 		author := getStringValue(postMap, "author")
 		url := getStringValue(postMap, "url")
 
-		isDuplicate, err := checkContentDuplicate(ctx, db, contentHash, platform, author, url)
+		// 入库前按长度限制截断，标题+作者去重与已入库的截断标题比对
+		storedTitle, titleTruncated := utils.TruncateRunes(title, limits.MaxTitleLength)
+		storedContent, contentTruncated := utils.TruncateRunes(contentText, limits.MaxContentLength)
+
+		simHash := utils.SimHash(title + " " + contentText)
+
+		duplicate, err := dedup.CheckDuplicate(ctx, services.DuplicateCheck{
+			ContentHash: contentHash,
+			Platform:    platform,
+			Author:      author,
+			Title:       storedTitle,
+			URL:         url,
+			SimHash:     simHash,
+			Since:       dedupSince,
+		})
 		if err != nil {
 			log.Printf("检查内容重复失败: %v", err)
+			summary.Errors++
 			continue
 		}
 
-		if isDuplicate {
-			duplicateCount++
-			log.Printf("跳过重复内容: hash=%s, title=%s", contentHash[:8], title)
+		if duplicate.Duplicate {
+			summary.Duplicates++
+			if duplicate.Type == services.DuplicateTypeSimHash {
+				log.Printf("跳过近似重复内容: hash=%s, title=%s, 相似于=%s, 距离=%d", contentHash[:8], title, duplicate.ExistingID.Hex(), duplicate.Distance)
+			} else {
+				log.Printf("跳过重复内容: hash=%s, title=%s, 类型=%s", contentHash[:8], title, duplicate.Type)
+			}
 			continue
 		}
 
@@ -400,20 +1199,27 @@ func SaveCrawlerContent(taskID primitive.ObjectID, posts []interface{}) error {
 			originID = fmt.Sprintf("%s_%d", contentHash[:8], time.Now().UnixNano())
 		}
 
+		// 哈希与指纹基于完整文本计算
 		content := models.CrawlerContent{
-			ID:          primitive.NewObjectID(),
-			TaskID:      taskID,
-			Title:       title,
-			Content:     contentText,
-			ContentHash: contentHash,
-			Author:      author,
-			Platform:    platform,
-			URL:         url,
-			OriginID:    originID,
-			Tags:        getStringArrayValue(postMap, "tags"),
-			Images:      getStringArrayValue(postMap, "images"),
-			VideoURL:    getStringValue(postMap, "video_url"),
-			CreatedAt:   time.Now(),
+			ID:           primitive.NewObjectID(),
+			TaskID:       taskID,
+			Title:        storedTitle,
+			Content:      storedContent,
+			Truncated:    titleTruncated || contentTruncated,
+			ContentHash:  contentHash,
+			SimHash:      utils.FormatSimHash(simHash),
+			SimHashBands: utils.SimHashBands(simHash),
+			Author:       author,
+			Platform:     platform,
+			URL:          url,
+			OriginID:     originID,
+			Tags:         getStringArrayValue(postMap, "tags"),
+			Images:       getStringArrayValue(postMap, "images"),
+			VideoURL:     getStringValue(postMap, "video_url"),
+			Likes:        getIntValue(postMap, "likes"),
+			Shares:       getIntValue(postMap, "shares"),
+			Comments:     getIntValue(postMap, "comments"),
+			CreatedAt:    time.Now(),
 		}
 
 		// 处理发布时间
@@ -423,63 +1229,109 @@ func SaveCrawlerContent(taskID primitive.ObjectID, posts []interface{}) error {
 			}
 		}
 
+		batchHashes[contentHash] = true
 		contents = append(contents, content)
+		if content.Truncated && limits.KeepFullText {
+			fullTexts = append(fullTexts, models.CrawlerContentFull{
+				ID:        content.ID,
+				TaskID:    taskID,
+				Title:     title,
+				Content:   contentText,
+				CreatedAt: content.CreatedAt,
+			})
+		}
 	}
 
-	var savedCount int
-	if len(contents) > 0 {
-		_, err := db.Collection("crawler_contents").InsertMany(ctx, contents)
-		if err != nil {
+	if len(contents) == 0 {
+		log.Printf("内容处理完成: 总数=%d, 保存=0, 去重=%d, 失败=%d", summary.Total, summary.Duplicates, summary.Errors)
+		return summary, nil
+	}
+
+	// ordered:false 时单条失败不影响其余条目写入
+	_, err = db.Collection("crawler_contents").InsertMany(ctx, contents, options.InsertMany().SetOrdered(false))
+	rejected := make(map[int]bool)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
 			log.Printf("保存爬取内容失败: %v", err)
-			return err
+			return summary, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			rejected[writeErr.Index] = true
+			if mongo.IsDuplicateKeyError(writeErr) {
+				summary.Duplicates++
+			} else {
+				summary.Errors++
+				log.Printf("保存爬取内容失败: %v", writeErr.Message)
+			}
 		}
-		savedCount = len(contents)
 	}
 
-	log.Printf("内容处理完成: 总数=%d, 保存=%d, 去重=%d", len(posts), savedCount, duplicateCount)
-	return nil
-}
-
-// generateContentHash 生成内容哈希
-func generateContentHash(content string) string {
-	// 标准化内容：去除多余空格、换行等
-	normalized := strings.TrimSpace(strings.ReplaceAll(content, "\n", " "))
-	normalized = strings.ReplaceAll(normalized, "\r", "")
-
-	hash := sha256.Sum256([]byte(normalized))
-	return hex.EncodeToString(hash[:])
-}
+	saved := make([]interface{}, 0, len(contents)-len(rejected))
+	savedIDs := make(map[primitive.ObjectID]bool, len(contents))
+	for i, content := range contents {
+		if rejected[i] {
+			continue
+		}
+		saved = append(saved, content)
+		savedIDs[content.(models.CrawlerContent).ID] = true
+	}
+	summary.Saved = len(saved)
 
-// checkContentDuplicate 检查内容是否重复
-func checkContentDuplicate(ctx context.Context, db *mongo.Database, contentHash, platform, author, url string) (bool, error) {
-	// 优先检查内容哈希
-	filter := bson.M{"content_hash": contentHash}
+	if len(fullTexts) > 0 {
+		var savedFullTexts []interface{}
+		for _, fullText := range fullTexts {
+			if savedIDs[fullText.(models.CrawlerContentFull).ID] {
+				savedFullTexts = append(savedFullTexts, fullText)
+			}
+		}
+		if len(savedFullTexts) > 0 {
+			if _, err := db.Collection("crawler_content_full").InsertMany(ctx, savedFullTexts); err != nil {
+				log.Printf("保存完整文本失败: %v", err)
+			}
+		}
+	}
 
-	count, err := db.Collection("crawler_contents").CountDocuments(ctx, filter)
-	if err != nil {
-		return false, err
+	// 为视频内容生成封面图（耗时操作，异步执行）
+	if len(saved) > 0 && config.IsVideoPosterEnabled() {
+		go attachVideoPosters(saved)
 	}
 
-	if count > 0 {
-		return true, nil
+	log.Printf("内容处理完成: 总数=%d, 保存=%d, 去重=%d, 失败=%d", summary.Total, summary.Saved, summary.Duplicates, summary.Errors)
+	return summary, nil
+}
+
+// attachVideoPosters 为带视频链接的内容提取封面并写回poster_url，失败时跳过
+func attachVideoPosters(contents []interface{}) {
+	posterService := services.NewPosterService()
+	if !posterService.Available() {
+		log.Printf("未检测到ffmpeg，跳过视频封面生成")
+		return
 	}
 
-	// 如果有URL，也检查URL是否重复
-	if url != "" {
-		urlFilter := bson.M{
-			"url":      url,
-			"platform": platform,
+	db := config.GetDB()
+	for _, item := range contents {
+		content, ok := item.(models.CrawlerContent)
+		if !ok || content.VideoURL == "" {
+			continue
 		}
-		urlCount, err := db.Collection("crawler_contents").CountDocuments(ctx, urlFilter)
+
+		fileInfo, err := posterService.CreatePoster(context.Background(), content.VideoURL)
 		if err != nil {
-			return false, err
+			log.Printf("生成视频封面失败: id=%s, %v", content.ID.Hex(), err)
+			continue
 		}
-		if urlCount > 0 {
-			return true, nil
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = db.Collection("crawler_contents").UpdateOne(ctx,
+			bson.M{"_id": content.ID},
+			bson.M{"$set": bson.M{"poster_url": fileInfo.URL}},
+		)
+		cancel()
+		if err != nil {
+			log.Printf("保存视频封面失败: id=%s, %v", content.ID.Hex(), err)
 		}
 	}
-
-	return false, nil
 }
 
 // 辅助函数
@@ -492,6 +1344,40 @@ func getStringValue(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// getIntValue 读取计数字段，兼容JSON解码出的float64及数字字符串，缺失、无效或为负时返回0
+func getIntValue(m map[string]interface{}, key string) int {
+	var value float64
+	switch v := m[key].(type) {
+	case float64:
+		value = v
+	case int:
+		value = float64(v)
+	case int64:
+		value = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return 0
+		}
+		value = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0
+		}
+		value = parsed
+	default:
+		return 0
+	}
+	if math.IsNaN(value) || value <= 0 {
+		return 0
+	}
+	if value > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(value)
+}
+
 func getStringArrayValue(m map[string]interface{}, key string) []string {
 	if val, ok := m[key]; ok {
 		if arr, ok := val.([]interface{}); ok {
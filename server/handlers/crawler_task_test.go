@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestGetIntValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		{"JSON数字", float64(42), 42},
+		{"小数向零取整", 12.9, 12},
+		{"int", 7, 7},
+		{"int64", int64(1500), 1500},
+		{"json.Number", json.Number("300"), 300},
+		{"无效的json.Number", json.Number("abc"), 0},
+		{"数字字符串", "128", 128},
+		{"带空白的数字字符串", " 64 ", 64},
+		{"小数字符串", "3.7", 3},
+		{"非数字字符串", "1.2万", 0},
+		{"空字符串", "", 0},
+		{"负数视为0", float64(-5), 0},
+		{"负数字符串视为0", "-10", 0},
+		{"NaN视为0", math.NaN(), 0},
+		{"NaN字符串视为0", "NaN", 0},
+		{"超过int32上限时截断", float64(1e12), math.MaxInt32},
+		{"正无穷截断", math.Inf(1), math.MaxInt32},
+		{"布尔值", true, 0},
+		{"nil", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getIntValue(map[string]interface{}{"likes": tt.value}, "likes")
+			if got != tt.want {
+				t.Errorf("getIntValue(%#v) = %d，期望 %d", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if got := getIntValue(map[string]interface{}{}, "likes"); got != 0 {
+		t.Errorf("缺少字段时 getIntValue = %d，期望 0", got)
+	}
+}
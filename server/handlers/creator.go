@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"newshub/config"
+	"newshub/crawler"
 	"newshub/models"
 )
 
@@ -21,12 +25,17 @@ func CreateCreator(c *gin.Context) {
 	}
 
 	// 设置默认值
+	creator.Platform = config.NormalizePlatform(creator.Platform)
 	if creator.DisplayName == "" {
 		creator.DisplayName = creator.Username
 	}
 	if creator.CrawlInterval == 0 {
 		creator.CrawlInterval = 60 // 默认60分钟
 	}
+	if !models.IsValidCrawlMode(creator.CrawlMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "crawl_mode must be incremental or full"})
+		return
+	}
 	creator.CrawlStatus = "idle"
 	creator.AutoCrawlEnabled = true // 默认启用自动爬取
 	creator.CreatedAt = time.Now()
@@ -64,7 +73,8 @@ func GetCreators(c *gin.Context) {
 		return
 	}
 
-	cursor, err := db.Collection("creators").Find(ctx, bson.M{})
+	opts := parsePagination(c, "creators").Apply(options.Find())
+	cursor, err := db.Collection("creators").Find(ctx, bson.M{}, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -84,6 +94,104 @@ func GetCreators(c *gin.Context) {
 	c.JSON(http.StatusOK, creators)
 }
 
+// UpdateCreator 更新创作者的爬取设置，仅修改请求中提供的字段
+func UpdateCreator(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		DisplayName      *string `json:"display_name"`
+		AutoCrawlEnabled *bool   `json:"auto_crawl_enabled"`
+		CrawlInterval    *int    `json:"crawl_interval"`
+		CrawlMode        *string `json:"crawl_mode"`
+		ProfileURL       *string `json:"profile_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CrawlInterval != nil && *req.CrawlInterval < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "crawl_interval must be at least 1 minute"})
+		return
+	}
+	if req.CrawlMode != nil && !models.IsValidCrawlMode(*req.CrawlMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "crawl_mode must be incremental or full"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coll := config.GetDB().Collection("creators")
+
+	var creator models.Creator
+	if err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&creator); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	update := bson.M{"$set": set}
+
+	if req.DisplayName != nil {
+		set["display_name"] = *req.DisplayName
+		creator.DisplayName = *req.DisplayName
+	}
+	if req.ProfileURL != nil {
+		set["profile_url"] = *req.ProfileURL
+		creator.ProfileURL = *req.ProfileURL
+	}
+	if req.CrawlMode != nil {
+		set["crawl_mode"] = *req.CrawlMode
+		creator.CrawlMode = *req.CrawlMode
+	}
+
+	scheduleChanged := false
+	if req.CrawlInterval != nil && *req.CrawlInterval != creator.CrawlInterval {
+		set["crawl_interval"] = *req.CrawlInterval
+		creator.CrawlInterval = *req.CrawlInterval
+		scheduleChanged = true
+	}
+	if req.AutoCrawlEnabled != nil && *req.AutoCrawlEnabled != creator.AutoCrawlEnabled {
+		set["auto_crawl_enabled"] = *req.AutoCrawlEnabled
+		creator.AutoCrawlEnabled = *req.AutoCrawlEnabled
+		scheduleChanged = true
+	}
+
+	// 与CreateCreator一致：启用自动爬取时按间隔计算下次爬取时间
+	if scheduleChanged {
+		if creator.AutoCrawlEnabled {
+			nextCrawl := now.Add(time.Duration(creator.CrawlInterval) * time.Minute)
+			set["next_crawl_at"] = nextCrawl
+			creator.NextCrawlAt = &nextCrawl
+		} else {
+			update["$unset"] = bson.M{"next_crawl_at": ""}
+			creator.NextCrawlAt = nil
+		}
+	}
+	creator.UpdatedAt = now
+
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, creator)
+}
+
 func DeleteCreator(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
@@ -107,3 +215,171 @@ func DeleteCreator(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Creator deleted successfully"})
 }
+
+// BulkSetCreatorAutoCrawl 批量启用/暂停创作者的自动爬取
+func BulkSetCreatorAutoCrawl(c *gin.Context) {
+	var req struct {
+		Enabled  *bool    `json:"enabled" binding:"required"`
+		Platform string   `json:"platform"`
+		IDs      []string `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 不传过滤条件时作用于全部创作者
+	filter := bson.M{}
+	if req.Platform != "" {
+		filter["platform"] = config.NormalizePlatform(req.Platform)
+	}
+	if len(req.IDs) > 0 {
+		objectIDs := make([]primitive.ObjectID, 0, len(req.IDs))
+		for _, idStr := range req.IDs {
+			objectID, err := primitive.ObjectIDFromHex(idStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID: " + idStr})
+				return
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+		filter["_id"] = bson.M{"$in": objectIDs}
+	}
+
+	now := time.Now()
+	var update interface{}
+	if *req.Enabled {
+		// 按各自的爬取间隔重新计算下次爬取时间
+		update = mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"auto_crawl_enabled": true,
+				"updated_at":         now,
+				"next_crawl_at": bson.M{"$add": bson.A{
+					now,
+					bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$crawl_interval", 60}}, 60 * 1000}},
+				}},
+			}}},
+		}
+	} else {
+		update = bson.M{
+			"$set": bson.M{
+				"auto_crawl_enabled": false,
+				"updated_at":         now,
+			},
+			"$unset": bson.M{"next_crawl_at": ""},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetDB().Collection("creators").UpdateMany(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Auto crawl updated successfully",
+		"enabled":        *req.Enabled,
+		"matched_count":  result.MatchedCount,
+		"modified_count": result.ModifiedCount,
+	})
+}
+
+// GetCreatorError 获取创作者最近一次的爬取错误
+func GetCreatorError(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var creator models.Creator
+	err = config.GetDB().Collection("creators").FindOne(ctx, bson.M{"_id": id}).Decode(&creator)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"creator_id":     creator.ID,
+		"display_name":   creator.DisplayName,
+		"platform":       creator.Platform,
+		"crawl_status":   creator.CrawlStatus,
+		"has_error":      creator.CrawlError != "",
+		"error":          creator.CrawlError,
+		"error_at":       creator.CrawlErrorAt,
+		"failure_streak": creator.CrawlFailures,
+		"last_crawl_at":  creator.LastCrawlAt,
+	})
+}
+
+// ClearCreatorError 清除创作者的爬取错误，失败状态恢复为idle
+func ClearCreatorError(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 使用聚合管道更新在一次写入中清除错误并恢复状态，正在爬取的创作者保持原状态
+	result, err := config.GetDB().Collection("creators").UpdateOne(ctx, bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"updated_at": time.Now(),
+			"crawl_status": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$crawl_status", "failed"}}, "idle", "$crawl_status",
+			}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"crawl_error", "crawl_error_at", "crawl_failure_streak"}}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Crawl error cleared successfully"})
+}
+
+// CrawlCreatorNow 立即爬取单个创作者，返回新保存的帖子数
+func CrawlCreatorNow(crawlerService *crawler.ScheduledCrawlerService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		savedCount, err := crawlerService.CrawlCreatorNow(c.Request.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, crawler.ErrCreatorNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
+			case errors.Is(err, crawler.ErrCreatorCrawling):
+				c.JSON(http.StatusConflict, gin.H{"error": "Creator is already being crawled"})
+			default:
+				c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Crawl completed",
+			"saved_count": savedCount,
+		})
+	}
+}
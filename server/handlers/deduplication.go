@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"newshub/config"
+	"newshub/models"
+	"newshub/services"
+	"newshub/utils"
+)
+
+// GetDeduplicationStats 获取去重统计
+func GetDeduplicationStats(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := services.GetDeduplicationService().GetStats(ctx)
+	if err != nil {
+		log.Printf("获取去重统计失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取去重统计失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// reprocessSampleLimit 结果中最多返回的重复内容ID数量
+const reprocessSampleLimit = 100
+
+// reprocessResult 重新去重的统计结果，运行中定期更新
+type reprocessResult struct {
+	Scanned      int            `json:"scanned"`
+	Duplicates   int            `json:"duplicates"`
+	ByType       map[string]int `json:"by_type"`
+	Removed      int64          `json:"removed"`
+	HashesFilled int            `json:"hashes_filled"`
+	Errors       int            `json:"errors"`
+	DuplicateIDs []string       `json:"duplicate_ids"`
+}
+
+// reprocessJob 重新去重任务，同一时间只运行一个，服务重启后状态丢失
+type reprocessJob struct {
+	ID         string          `json:"id"`
+	Status     string          `json:"status"` // running, completed
+	DryRun     bool            `json:"dry_run"`
+	Platform   string          `json:"platform,omitempty"`
+	TimedOut   bool            `json:"timed_out"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Result     reprocessResult `json:"result"`
+}
+
+var (
+	reprocessMutex sync.Mutex
+	reprocessLast  *reprocessJob // 正在运行或最近一次完成的任务
+)
+
+// ReprocessDeduplication 在后台按当前去重规则重新检查已入库的爬取内容，立即返回202
+// 按入库顺序遍历，每条内容只与更早入库的内容比对，保留最早的一条；缺失的内容哈希和SimHash会补算
+// dry_run 默认为true，只统计不删除；执行时间受 DEDUP_REPROCESS_TIMEOUT 限制，
+// 进度与结果通过 GET /api/deduplication/reprocess/status 查看；已有任务运行时返回409
+func ReprocessDeduplication(c *gin.Context) {
+	var req struct {
+		DryRun   *bool  `json:"dry_run"`
+		Platform string `json:"platform"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求数据格式错误"})
+			return
+		}
+	}
+
+	job := &reprocessJob{
+		ID:        primitive.NewObjectID().Hex(),
+		Status:    "running",
+		DryRun:    req.DryRun == nil || *req.DryRun,
+		Platform:  config.NormalizePlatform(req.Platform),
+		StartedAt: time.Now(),
+		Result:    reprocessResult{ByType: map[string]int{}, DuplicateIDs: []string{}},
+	}
+
+	reprocessMutex.Lock()
+	if reprocessLast != nil && reprocessLast.Status == "running" {
+		running := *reprocessLast
+		reprocessMutex.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "已有重新去重任务在运行", "job": running})
+		return
+	}
+	reprocessLast = job
+	snapshot := *job
+	reprocessMutex.Unlock()
+
+	go runReprocessJob(job)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "重新去重任务已开始",
+		"job":     snapshot,
+	})
+}
+
+// GetReprocessDeduplicationStatus 获取正在运行或最近一次重新去重任务的进度与结果
+func GetReprocessDeduplicationStatus(c *gin.Context) {
+	reprocessMutex.Lock()
+	defer reprocessMutex.Unlock()
+
+	if reprocessLast == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "尚未执行过重新去重任务"})
+		return
+	}
+	job := *reprocessLast
+	job.Result = job.Result.clone()
+	c.JSON(http.StatusOK, job)
+}
+
+// clone 复制结果，避免响应序列化时与后台任务并发访问map和切片
+func (r reprocessResult) clone() reprocessResult {
+	byType := make(map[string]int, len(r.ByType))
+	for k, v := range r.ByType {
+		byType[k] = v
+	}
+	r.ByType = byType
+	r.DuplicateIDs = append([]string{}, r.DuplicateIDs...)
+	return r
+}
+
+// runReprocessJob 执行重新去重任务并更新任务状态
+func runReprocessJob(job *reprocessJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetDedupReprocessTimeout())
+	defer cancel()
+
+	filter := bson.M{}
+	if job.Platform != "" {
+		filter["platform"] = job.Platform
+	}
+	result := reprocessContents(ctx, filter, job.DryRun, func(progress reprocessResult) {
+		reprocessMutex.Lock()
+		job.Result = progress.clone()
+		reprocessMutex.Unlock()
+	})
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	log.Printf("重新去重完成: 扫描=%d, 重复=%d, 删除=%d, 补算哈希=%d, 错误=%d, 预览=%v, 超时=%v",
+		result.Scanned, result.Duplicates, result.Removed, result.HashesFilled, result.Errors, job.DryRun, timedOut)
+
+	finishedAt := time.Now()
+	reprocessMutex.Lock()
+	job.Status = "completed"
+	job.TimedOut = timedOut
+	job.FinishedAt = &finishedAt
+	job.Result = result.clone()
+	reprocessMutex.Unlock()
+}
+
+// reprocessContents 遍历匹配的爬取内容重新判断是否重复，每处理一批调用一次progress
+// ctx 超时后停止遍历，已确认的重复内容仍会删除
+func reprocessContents(ctx context.Context, filter bson.M, dryRun bool, progress func(reprocessResult)) reprocessResult {
+	db := config.GetDB()
+	dedup := services.GetDeduplicationService()
+	result := reprocessResult{ByType: map[string]int{}, DuplicateIDs: []string{}}
+
+	cursor, err := db.Collection("crawler_contents").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(500))
+	if err != nil {
+		log.Printf("查询爬取内容失败: %v", err)
+		result.Errors++
+		return result
+	}
+	defer cursor.Close(context.Background())
+
+	var pending []primitive.ObjectID
+	markDuplicate := func(id primitive.ObjectID, duplicateType string) {
+		result.Duplicates++
+		result.ByType[duplicateType]++
+		if len(result.DuplicateIDs) < reprocessSampleLimit {
+			result.DuplicateIDs = append(result.DuplicateIDs, id.Hex())
+		}
+		if !dryRun {
+			pending = append(pending, id)
+		}
+	}
+	flush := func(ctx context.Context) {
+		if len(pending) == 0 {
+			return
+		}
+		deleted, err := deleteContentsByID(ctx, db, pending)
+		if err != nil {
+			log.Printf("删除重复内容失败: %v", err)
+			result.Errors += len(pending)
+		}
+		result.Removed += deleted
+		pending = pending[:0]
+	}
+
+	for cursor.Next(ctx) {
+		var content models.CrawlerContent
+		if err := cursor.Decode(&content); err != nil {
+			result.Errors++
+			continue
+		}
+		result.Scanned++
+
+		// 旧数据可能缺少哈希或指纹，按入库时的规则补算（截断内容使用完整文本）
+		contentHash := content.ContentHash
+		simHash, _ := utils.ParseSimHash(content.SimHash)
+		needsHash, needsSimHash := contentHash == "", content.SimHash == ""
+		if needsHash || needsSimHash {
+			title, text := content.Title, content.Content
+			if content.Truncated {
+				var full models.CrawlerContentFull
+				if err := db.Collection("crawler_content_full").FindOne(ctx, bson.M{"_id": content.ID}).Decode(&full); err == nil {
+					title, text = full.Title, full.Content
+				}
+			}
+			if needsHash {
+				contentHash = utils.TitleContentHash(title, text)
+			}
+			if needsSimHash {
+				simHash = utils.SimHash(title + " " + text)
+			}
+		}
+
+		duplicate, err := dedup.IsDuplicate(ctx, services.DuplicateCheck{
+			ContentHash: contentHash,
+			Platform:    content.Platform,
+			Author:      content.Author,
+			Title:       content.Title,
+			URL:         content.URL,
+			SimHash:     simHash,
+			BeforeID:    content.ID,
+			At:          content.CreatedAt,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("检查内容重复失败: id=%s, %v", content.ID.Hex(), err)
+			result.Errors++
+			continue
+		}
+		if duplicate.Duplicate {
+			markDuplicate(content.ID, duplicate.Type)
+		} else if !dryRun && (needsHash || needsSimHash) {
+			update := bson.M{}
+			if needsHash {
+				update["content_hash"] = contentHash
+			}
+			if needsSimHash {
+				update["simhash"] = utils.FormatSimHash(simHash)
+				update["simhash_bands"] = utils.SimHashBands(simHash)
+			}
+			_, err := db.Collection("crawler_contents").UpdateOne(ctx, bson.M{"_id": content.ID}, bson.M{"$set": update})
+			switch {
+			case mongo.IsDuplicateKeyError(err):
+				// 补算的哈希与其他内容冲突，说明是重复内容
+				markDuplicate(content.ID, services.DuplicateTypeContentHash)
+			case err != nil:
+				log.Printf("补充内容哈希失败: id=%s, %v", content.ID.Hex(), err)
+				result.Errors++
+			default:
+				result.HashesFilled++
+			}
+		}
+
+		if len(pending) >= 500 {
+			flush(ctx)
+		}
+		if result.Scanned%500 == 0 {
+			progress(result)
+		}
+	}
+	if err := cursor.Err(); err != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("遍历爬取内容失败: %v", err)
+		result.Errors++
+	}
+
+	// 超时后仍需删除已确认的重复内容
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	flush(flushCtx)
+	flushCancel()
+
+	return result
+}
+
+// deleteContentsByID 删除爬取内容及其完整文本和物化帖子
+func deleteContentsByID(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	result, err := db.Collection("crawler_contents").DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.Collection("crawler_content_full").DeleteMany(ctx, filter); err != nil {
+		log.Printf("删除完整文本失败: %v", err)
+	}
+	postFilter := bson.M{"$or": []bson.M{
+		{"source_content_id": bson.M{"$in": ids}},
+		{"_id": bson.M{"$in": ids}}, // 记录来源字段之前物化的帖子与内容同ID
+	}}
+	if _, err := db.Collection("posts").DeleteMany(ctx, postFilter); err != nil {
+		log.Printf("删除物化帖子失败: %v", err)
+	}
+	return result.DeletedCount, nil
+}
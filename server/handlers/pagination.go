@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"newshub/config"
+)
+
+// Pagination 从请求中解析出的分页参数
+type Pagination struct {
+	Limit int64 // 0表示不限制
+	Page  int64
+	Skip  int64
+}
+
+// parsePagination 解析 limit/page 查询参数，默认值与上限取自资源的分页配置
+func parsePagination(c *gin.Context, resource string) Pagination {
+	size := config.GetPageSize(resource)
+
+	limit := int64(size.Default)
+	if parsed, err := strconv.ParseInt(c.Query("limit"), 10, 64); err == nil && parsed > 0 {
+		limit = parsed
+	}
+	if size.Max > 0 && limit > int64(size.Max) {
+		limit = int64(size.Max)
+	}
+
+	page := int64(1)
+	if parsed, err := strconv.ParseInt(c.Query("page"), 10, 64); err == nil && parsed > 0 {
+		page = parsed
+	}
+
+	return Pagination{
+		Limit: limit,
+		Page:  page,
+		Skip:  (page - 1) * limit,
+	}
+}
+
+// Apply 将分页参数应用到查询选项
+func (p Pagination) Apply(opts *options.FindOptions) *options.FindOptions {
+	if p.Limit > 0 {
+		opts.SetLimit(p.Limit)
+	}
+	if p.Skip > 0 {
+		opts.SetSkip(p.Skip)
+	}
+	return opts
+}
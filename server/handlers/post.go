@@ -2,20 +2,24 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"net/http"
-	"strconv"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"newshub/config"
 	"newshub/models"
 )
 
-// GetPosts 获取帖子列表（从crawler_contents集合获取并转换）
+// GetPosts 获取帖子列表
+// 启用POSTS_MATERIALIZED时直接读取posts集合（需先调用MaterializePosts），否则从crawler_contents获取并转换
 func GetPosts(c *gin.Context) {
 	var posts []models.Post
 
@@ -24,29 +28,62 @@ func GetPosts(c *gin.Context) {
 
 	// 获取查询参数
 	creatorID := c.Query("creator_id")
-	platform := c.Query("platform")
-	limitStr := c.Query("limit")
-
-	// 设置默认限制
-	limit := 50
-	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
+	platform := config.NormalizePlatform(c.Query("platform"))
+	pagination := parsePagination(c, "posts")
+	materialized := config.IsPostsMaterialized()
 
 	// 构建查询条件
 	filter := bson.M{}
 	if platform != "" {
 		filter["platform"] = platform
 	}
+	// q：标题或正文包含关键词（不区分大小写），按字面匹配
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"title": pattern},
+			bson.M{"content": pattern},
+		}
+	}
+	// tag：标签数组中包含该标签
+	if tag := strings.TrimSpace(c.Query("tag")); tag != "" {
+		filter["tags"] = tag
+	}
 	if creatorID != "" {
+		if materialized {
+			objectID, err := primitive.ObjectIDFromHex(creatorID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid creator_id"})
+				return
+			}
+			filter["creator_id"] = objectID
+		}
 		// 对于crawler_contents，我们可能需要通过author字段匹配
 		// 这里暂时跳过creator_id过滤，因为crawler_contents没有creator_id字段
 	}
 
+	opts := pagination.Apply(options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+
+	if materialized {
+		cursor, err := config.GetDB().Collection("posts").Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err := cursor.All(ctx, &posts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if posts == nil {
+			posts = []models.Post{}
+		}
+		c.JSON(http.StatusOK, posts)
+		return
+	}
+
 	// 查询crawler_contents，按创建时间倒序
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
 	cursor, err := config.GetDB().Collection("crawler_contents").Find(ctx, filter, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -63,33 +100,8 @@ func GetPosts(c *gin.Context) {
 
 	// 转换crawler_contents为posts格式
 	for _, content := range crawlerContents {
-		// 创建基础的Post结构
-		post := models.Post{
-			ID:          content.ID,
-			CreatorID:   content.TaskID, // 使用TaskID作为CreatorID的临时方案
-			CreatorName: content.Author,
-			Platform:    content.Platform,
-			PostID:      content.OriginID,
-			Title:       content.Title,
-			Content:     content.Content,
-			MediaURLs:   []string{},
-			PublishedAt: content.PublishedAt,
-			CreatedAt:   content.CreatedAt,
-		}
-		
-		// 处理媒体URLs：添加图片
-		if len(content.Images) > 0 {
-			post.MediaURLs = append(post.MediaURLs, content.Images...)
-			// 设置第一张图片作为imageUrl
-			post.ImageUrl = content.Images[0]
-		}
-		
-		// 处理视频URL
-		if content.VideoURL != "" {
-			post.MediaURLs = append(post.MediaURLs, content.VideoURL)
-			post.VideoUrl = content.VideoURL
-		}
-		
+		post := contentToPost(content)
+		post.CreatorID = content.TaskID // 使用TaskID作为CreatorID的临时方案
 		posts = append(posts, post)
 	}
 
@@ -101,6 +113,202 @@ func GetPosts(c *gin.Context) {
 	c.JSON(http.StatusOK, posts)
 }
 
+// contentToPost 将爬取内容转换为帖子（不含CreatorID）
+func contentToPost(content models.CrawlerContent) models.Post {
+	// 创建基础的Post结构
+	post := models.Post{
+		ID:              content.ID,
+		SourceContentID: content.ID,
+		CreatorName:     content.Author,
+		Platform:        content.Platform,
+		PostID:          content.OriginID,
+		Title:           content.Title,
+		Content:         content.Content,
+		ContentHash:     content.ContentHash,
+		Tags:            content.Tags,
+		MediaURLs:       []string{},
+		Likes:           content.Likes,
+		Shares:          content.Shares,
+		Comments:        content.Comments,
+		PublishedAt:     content.PublishedAt,
+		CreatedAt:       content.CreatedAt,
+	}
+
+	// 处理媒体URLs：添加图片
+	if len(content.Images) > 0 {
+		post.MediaURLs = append(post.MediaURLs, content.Images...)
+		// 设置第一张图片作为imageUrl
+		post.ImageUrl = content.Images[0]
+	}
+
+	// 没有图片时使用视频封面作为展示图
+	if post.ImageUrl == "" && content.PosterURL != "" {
+		post.ImageUrl = content.PosterURL
+	}
+
+	// 处理视频URL
+	if content.VideoURL != "" {
+		post.MediaURLs = append(post.MediaURLs, content.VideoURL)
+		post.VideoUrl = content.VideoURL
+	}
+
+	return post
+}
+
+// MaterializePosts 将crawler_contents转换后写入posts集合
+// 按content_hash去重，已存在的帖子不会被覆盖；since（RFC3339）可只处理之后新增的内容
+func MaterializePosts(c *gin.Context) {
+	filter := bson.M{}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since 必须是RFC3339格式的时间"})
+			return
+		}
+		filter["created_at"] = bson.M{"$gte": since}
+	}
+
+	db := config.GetDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	creatorIDs, err := loadCreatorLookup(ctx, db)
+	if err != nil {
+		log.Printf("加载创作者失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载创作者失败"})
+		return
+	}
+
+	cursor, err := db.Collection("crawler_contents").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		log.Printf("查询爬取内容失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询爬取内容失败"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	const batchSize = 500
+	var (
+		scanned  int
+		inserted int64
+		updated  int64
+		linked   int
+		batch    []mongo.WriteModel
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := db.Collection("posts").BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			inserted += result.UpsertedCount
+			updated += result.ModifiedCount
+		}
+		batch = batch[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var content models.CrawlerContent
+		if err := cursor.Decode(&content); err != nil {
+			log.Printf("解析爬取内容失败: %v", err)
+			continue
+		}
+		scanned++
+
+		post := contentToPost(content)
+		creatorID, ok := creatorIDs[content.Platform+"|"+content.Author]
+		if ok {
+			post.CreatorID = creatorID
+			linked++
+		}
+
+		// 按来源内容ID对应物化帖子，删除内容时据此级联删除；兼容记录来源字段之前与内容同ID的帖子
+		key := bson.M{"$or": []bson.M{{"source_content_id": content.ID}, {"_id": content.ID}}}
+		batch = append(batch, mongo.NewUpdateOneModel().
+			SetFilter(key).
+			SetUpdate(materializeUpdate(post, ok)).
+			SetUpsert(true))
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				log.Printf("写入帖子失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "写入帖子失败", "scanned": scanned, "inserted": inserted, "updated": updated})
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		log.Printf("写入帖子失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "写入帖子失败", "scanned": scanned, "inserted": inserted, "updated": updated})
+		return
+	}
+
+	log.Printf("帖子物化完成: 扫描=%d, 新增=%d, 更新=%d, 关联创作者=%d", scanned, inserted, updated, linked)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "帖子物化完成",
+		"scanned":  scanned,
+		"inserted": inserted,
+		"updated":  updated,
+		"linked":   linked,
+	})
+}
+
+// materializeUpdate 构造物化帖子的upsert更新：可变字段每次覆盖为最新的爬取内容，
+// _id 与创建时间只在新增时写入；未匹配到创作者时不覆盖已有的关联
+func materializeUpdate(post models.Post, linked bool) bson.M {
+	set := bson.M{
+		"creator_name":      post.CreatorName,
+		"platform":          post.Platform,
+		"post_id":           post.PostID,
+		"title":             post.Title,
+		"content":           post.Content,
+		"content_hash":      post.ContentHash,
+		"source_content_id": post.SourceContentID,
+		"tags":              post.Tags,
+		"media_urls":        post.MediaURLs,
+		"image_url":         post.ImageUrl,
+		"video_url":         post.VideoUrl,
+		"likes":             post.Likes,
+		"shares":            post.Shares,
+		"comments":          post.Comments,
+		"published_at":      post.PublishedAt,
+	}
+	setOnInsert := bson.M{
+		"_id":        post.ID,
+		"created_at": post.CreatedAt,
+	}
+	if linked {
+		set["creator_id"] = post.CreatorID
+	} else {
+		setOnInsert["creator_id"] = post.CreatorID
+	}
+	return bson.M{"$set": set, "$setOnInsert": setOnInsert}
+}
+
+// loadCreatorLookup 构建 "平台|用户名" 及 "平台|显示名称" 到创作者ID的映射
+func loadCreatorLookup(ctx context.Context, db *mongo.Database) (map[string]primitive.ObjectID, error) {
+	cursor, err := db.Collection("creators").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var creators []models.Creator
+	if err := cursor.All(ctx, &creators); err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[string]primitive.ObjectID, len(creators)*2)
+	for _, creator := range creators {
+		if creator.DisplayName != "" {
+			lookup[creator.Platform+"|"+creator.DisplayName] = creator.ID
+		}
+		lookup[creator.Platform+"|"+creator.Username] = creator.ID
+	}
+	return lookup, nil
+}
+
 // GetPost 获取单个帖子详情
 func GetPost(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
@@ -145,5 +353,15 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
+	// 同时删除可能存在的完整文本
+	if _, err := config.GetDB().Collection("crawler_content_full").DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		log.Printf("删除完整文本失败: %v", err)
+	}
+	// 以及物化到posts集合中的副本
+	postFilter := bson.M{"$or": []bson.M{{"source_content_id": id}, {"_id": id}}}
+	if _, err := config.GetDB().Collection("posts").DeleteMany(ctx, postFilter); err != nil {
+		log.Printf("删除物化帖子失败: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
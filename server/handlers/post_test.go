@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"newshub/models"
+)
+
+// TestMaterializeUpdateRecordsSourceContent 物化帖子应记录来源内容ID，删除内容时据此级联删除
+func TestMaterializeUpdateRecordsSourceContent(t *testing.T) {
+	content := models.CrawlerContent{ID: primitive.NewObjectID(), Title: "标题", Content: "正文", ContentHash: "hash"}
+
+	for _, linked := range []bool{true, false} {
+		update := materializeUpdate(contentToPost(content), linked)
+		set, _ := update["$set"].(bson.M)
+		if got := set["source_content_id"]; got != content.ID {
+			t.Errorf("linked=%v 时 source_content_id = %v，期望 %v", linked, got, content.ID)
+		}
+	}
+}
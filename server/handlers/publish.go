@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -16,14 +17,19 @@ import (
 
 	"newshub/config"
 	"newshub/models"
+	"newshub/services"
 )
 
 type CreatePublishTaskRequest struct {
 	VideoID     primitive.ObjectID `json:"videoId"`
 	Platforms   []string           `json:"platforms"`
 	Description string             `json:"description"`
+	Force       bool               `json:"force"` // 忽略重复发布检查
 }
 
+// activePublishStatuses 视为已发布或正在发布的任务状态
+var activePublishStatuses = []string{"pending", "processing", "published", "partial"}
+
 func CreatePublishTask(c *gin.Context) {
 	var req CreatePublishTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -42,6 +48,41 @@ func CreatePublishTask(c *gin.Context) {
 		return
 	}
 
+	// 去除重复的平台
+	platforms := make([]string, 0, len(req.Platforms))
+	seen := make(map[string]bool)
+	for _, platform := range req.Platforms {
+		platform = config.NormalizePlatform(platform)
+		if platform != "" && !seen[platform] {
+			seen[platform] = true
+			platforms = append(platforms, platform)
+		}
+	}
+	if len(platforms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要指定一个发布平台"})
+		return
+	}
+	req.Platforms = platforms
+
+	// 检查同一视频是否已经发布（或正在发布）到相同平台
+	if !req.Force {
+		conflicts, existingTaskID, err := findPublishConflicts(ctx, req.VideoID, req.Platforms)
+		if err != nil {
+			log.Printf("检查重复发布失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查重复发布失败"})
+			return
+		}
+		if len(conflicts) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":            "视频已发布到相同平台",
+				"message":          "如需重新发布请设置force为true",
+				"platforms":        conflicts,
+				"existing_task_id": existingTaskID,
+			})
+			return
+		}
+	}
+
 	task := models.PublishTask{
 		VideoID:     req.VideoID,
 		Platforms:   req.Platforms,
@@ -141,6 +182,46 @@ func UpdatePublishTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
+// findPublishConflicts 查找该视频已发布或正在发布的平台
+func findPublishConflicts(ctx context.Context, videoID primitive.ObjectID, platforms []string) ([]string, string, error) {
+	filter := bson.M{
+		"video_id":  videoID,
+		"platforms": bson.M{"$in": platforms},
+		"status":    bson.M{"$in": activePublishStatuses},
+	}
+
+	cursor, err := config.GetDB().Collection("publish_tasks").Find(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []models.PublishTask
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, "", err
+	}
+
+	requested := make(map[string]bool, len(platforms))
+	for _, platform := range platforms {
+		requested[platform] = true
+	}
+
+	var conflicts []string
+	var existingTaskID string
+	for _, task := range tasks {
+		for _, platform := range task.Platforms {
+			if requested[platform] {
+				conflicts = append(conflicts, platform)
+				requested[platform] = false
+				if existingTaskID == "" {
+					existingTaskID = task.ID.Hex()
+				}
+			}
+		}
+	}
+	return conflicts, existingTaskID, nil
+}
+
 // publishVideoAsync 异步发布视频到各个平台
 func publishVideoAsync(taskID, videoID primitive.ObjectID, platforms []string, description string) {
 	log.Printf("开始发布任务: %s, 视频: %s, 平台: %v", taskID.Hex(), videoID.Hex(), platforms)
@@ -155,30 +236,54 @@ func publishVideoAsync(taskID, videoID primitive.ObjectID, platforms []string, d
 		return
 	}
 
-	// 检查视频文件是否存在
-	videoPath := config.GetVideoPath(videoID.Hex())
-	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
-		updatePublishTaskStatus(taskID, "failed", "视频文件不存在", "")
+	// 获取视频文件（存放在MinIO时下载到临时文件）
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	videoPath, cleanup, err := services.NewStorageService().ResolveVideoFile(ctx, videoID.Hex(), video.ObjectName)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			updatePublishTaskStatus(taskID, "failed", "视频文件不存在", "")
+		} else {
+			updatePublishTaskStatus(taskID, "failed", fmt.Sprintf("获取视频文件失败: %v", err), "")
+		}
 		return
 	}
+	defer cleanup()
 
 	var publishResults []string
 	var publishErrors []string
+	var outcomes []models.PublishOutcome
 
 	// 逐个平台发布
 	for _, platform := range platforms {
 		log.Printf("发布到平台: %s", platform)
-		result, err := publishToPlatform(platform, videoPath, description, video)
+		// 同一任务同一平台的幂等键固定，重复执行不会在平台侧重复发布
+		idempotencyKey := taskID.Hex() + ":" + platform
+		result, err := publishToPlatform(idempotencyKey, platform, videoPath, description, video)
+
+		outcome := models.PublishOutcome{
+			Platform:       platform,
+			URL:            result.URL,
+			Simulated:      result.Simulated,
+			Attempts:       result.Attempts,
+			IdempotencyKey: idempotencyKey,
+		}
 		if err != nil {
+			outcome.Error = err.Error()
 			errorMsg := fmt.Sprintf("%s发布失败: %v", platform, err)
 			publishErrors = append(publishErrors, errorMsg)
 			log.Printf(errorMsg)
 		} else {
-			successMsg := fmt.Sprintf("%s发布成功: %s", platform, result)
+			successMsg := fmt.Sprintf("%s发布成功: %s", platform, result.URL)
+			if result.Simulated {
+				successMsg = fmt.Sprintf("%s模拟发布: %s", platform, result.URL)
+			}
 			publishResults = append(publishResults, successMsg)
 			log.Printf(successMsg)
 		}
+		outcomes = append(outcomes, outcome)
 	}
+	savePublishOutcomes(taskID, outcomes)
 
 	// 更新最终状态
 	if len(publishErrors) == 0 {
@@ -214,24 +319,31 @@ func getVideoInfo(videoID primitive.ObjectID) (*models.Video, error) {
 	return &video, nil
 }
 
-// publishToPlatform 发布到指定平台
-func publishToPlatform(platform, videoPath, description string, video *models.Video) (string, error) {
+// PublishResult 单个平台的发布结果
+type PublishResult struct {
+	URL       string
+	Simulated bool // 未配置平台凭证时为模拟发布
+	Attempts  int  // 实际调用平台API的次数
+}
+
+// publishToPlatform 发布到指定平台，idempotencyKey 用于平台侧去重，重试时保持不变
+func publishToPlatform(idempotencyKey, platform, videoPath, description string, video *models.Video) (PublishResult, error) {
 	switch platform {
 	case "weibo":
-		return publishToWeibo(videoPath, description, video)
+		return publishToWeibo(idempotencyKey, videoPath, description, video)
 	case "douyin":
-		return publishToDouyin(videoPath, description, video)
+		return publishToDouyin(idempotencyKey, videoPath, description, video)
 	case "xiaohongshu":
-		return publishToXiaohongshu(videoPath, description, video)
+		return publishToXiaohongshu(idempotencyKey, videoPath, description, video)
 	case "bilibili":
-		return publishToBilibili(videoPath, description, video)
+		return publishToBilibili(idempotencyKey, videoPath, description, video)
 	default:
-		return "", fmt.Errorf("不支持的平台: %s", platform)
+		return PublishResult{}, fmt.Errorf("不支持的平台: %s", platform)
 	}
 }
 
 // publishToWeibo 发布到微博
-func publishToWeibo(videoPath, description string, video *models.Video) (string, error) {
+func publishToWeibo(idempotencyKey, videoPath, description string, video *models.Video) (PublishResult, error) {
 	appKey := os.Getenv("WEIBO_APP_KEY")
 	appSecret := os.Getenv("WEIBO_APP_SECRET")
 
@@ -242,11 +354,11 @@ func publishToWeibo(videoPath, description string, video *models.Video) (string,
 
 	// 实际的微博API调用逻辑
 	// 这里需要根据微博API文档实现具体的发布逻辑
-	return callPlatformAPI("weibo", appKey, appSecret, videoPath, description)
+	return callPlatformAPI("weibo", appKey, appSecret, idempotencyKey, videoPath, description)
 }
 
 // publishToDouyin 发布到抖音
-func publishToDouyin(videoPath, description string, video *models.Video) (string, error) {
+func publishToDouyin(idempotencyKey, videoPath, description string, video *models.Video) (PublishResult, error) {
 	appKey := os.Getenv("DOUYIN_APP_KEY")
 	appSecret := os.Getenv("DOUYIN_APP_SECRET")
 
@@ -256,11 +368,11 @@ func publishToDouyin(videoPath, description string, video *models.Video) (string
 	}
 
 	// 实际的抖音API调用逻辑
-	return callPlatformAPI("douyin", appKey, appSecret, videoPath, description)
+	return callPlatformAPI("douyin", appKey, appSecret, idempotencyKey, videoPath, description)
 }
 
 // publishToXiaohongshu 发布到小红书
-func publishToXiaohongshu(videoPath, description string, video *models.Video) (string, error) {
+func publishToXiaohongshu(idempotencyKey, videoPath, description string, video *models.Video) (PublishResult, error) {
 	appKey := os.Getenv("XIAOHONGSHU_APP_KEY")
 	appSecret := os.Getenv("XIAOHONGSHU_APP_SECRET")
 
@@ -270,11 +382,11 @@ func publishToXiaohongshu(videoPath, description string, video *models.Video) (s
 	}
 
 	// 实际的小红书API调用逻辑
-	return callPlatformAPI("xiaohongshu", appKey, appSecret, videoPath, description)
+	return callPlatformAPI("xiaohongshu", appKey, appSecret, idempotencyKey, videoPath, description)
 }
 
 // publishToBilibili 发布到B站
-func publishToBilibili(videoPath, description string, video *models.Video) (string, error) {
+func publishToBilibili(idempotencyKey, videoPath, description string, video *models.Video) (PublishResult, error) {
 	appKey := os.Getenv("BILIBILI_APP_KEY")
 	appSecret := os.Getenv("BILIBILI_APP_SECRET")
 
@@ -284,11 +396,11 @@ func publishToBilibili(videoPath, description string, video *models.Video) (stri
 	}
 
 	// 实际的B站API调用逻辑
-	return callPlatformAPI("bilibili", appKey, appSecret, videoPath, description)
+	return callPlatformAPI("bilibili", appKey, appSecret, idempotencyKey, videoPath, description)
 }
 
 // simulatePublish 模拟发布（用于开发和测试）
-func simulatePublish(platform, videoPath, description string) (string, error) {
+func simulatePublish(platform, videoPath, description string) (PublishResult, error) {
 	log.Printf("模拟发布到%s: 视频=%s, 描述=%s", platform, videoPath, description)
 
 	// 模拟处理时间
@@ -296,64 +408,107 @@ func simulatePublish(platform, videoPath, description string) (string, error) {
 
 	// 生成模拟的发布URL
 	mockURL := fmt.Sprintf("https://%s.com/video/%d", platform, time.Now().Unix())
-	return mockURL, nil
+	return PublishResult{URL: mockURL, Simulated: true}, nil
 }
 
-// callPlatformAPI 调用平台API
-func callPlatformAPI(platform, appKey, appSecret, videoPath, description string) (string, error) {
+// publishMaxAttempts 调用平台API的最大尝试次数
+const publishMaxAttempts = 3
+
+// callPlatformAPI 调用平台API，网络错误、429和5xx时按1s、2s退避重试
+// 每次重试携带相同的Idempotency-Key，避免平台重复发布；失败时结果中同样记录实际尝试次数
+func callPlatformAPI(platform, appKey, appSecret, idempotencyKey, videoPath, description string) (PublishResult, error) {
 	// 这里实现具体的平台API调用逻辑
 	// 每个平台的API都不同，需要根据具体的API文档来实现
 
 	// 准备请求数据
 	requestData := map[string]interface{}{
-		"app_key":     appKey,
-		"app_secret":  appSecret,
-		"video_path":  videoPath,
-		"description": description,
-		"platform":    platform,
+		"app_key":         appKey,
+		"app_secret":      appSecret,
+		"video_path":      videoPath,
+		"description":     description,
+		"platform":        platform,
+		"idempotency_key": idempotencyKey,
 	}
 
 	jsonData, err := json.Marshal(requestData)
 	if err != nil {
-		return "", err
+		return PublishResult{}, err
 	}
 
 	// 构建API端点URL（这里使用示例URL）
 	apiURL := fmt.Sprintf("https://api.%s.com/upload", platform)
+	client := &http.Client{Timeout: 2 * time.Minute}
+
+	var (
+		lastErr  error
+		attempts int
+	)
+	for attempt := 1; attempt <= publishMaxAttempts; attempt++ {
+		attempts = attempt
+		if attempt > 1 {
+			backoff := time.Duration(1<<(attempt-2)) * time.Second
+			log.Printf("%s发布第%d次重试，等待%v: %v", platform, attempt-1, backoff, lastErr)
+			time.Sleep(backoff)
+		}
+
+		publishURL, retryable, err := doPlatformRequest(client, apiURL, appKey, idempotencyKey, jsonData)
+		if err == nil {
+			return PublishResult{URL: publishURL, Attempts: attempt}, nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+
+	return PublishResult{Attempts: attempts}, lastErr
+}
 
-	// 发送HTTP请求
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+// doPlatformRequest 发送一次平台API请求，返回发布URL以及失败时是否可重试
+func doPlatformRequest(client *http.Client, apiURL, appKey, idempotencyKey string, body []byte) (string, bool, error) {
+	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(body))
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+appKey)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
 
-	client := &http.Client{Timeout: 2 * time.Minute}
 	resp, err := client.Do(req)
 	if err != nil {
-		// 如果API调用失败，回退到模拟发布
-		log.Printf("API调用失败，使用模拟发布: %v", err)
-		return simulatePublish(platform, videoPath, description)
+		return "", true, fmt.Errorf("平台API调用失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("平台API返回错误: %d", resp.StatusCode)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return "", retryable, fmt.Errorf("平台API返回错误: %d", resp.StatusCode)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	publishURL, ok := result["url"].(string)
 	if !ok {
-		return "", fmt.Errorf("无效的平台API响应")
+		return "", false, fmt.Errorf("无效的平台API响应")
 	}
 
-	return publishURL, nil
+	return publishURL, false, nil
+}
+
+// savePublishOutcomes 保存各平台的发布结果
+func savePublishOutcomes(taskID primitive.ObjectID, outcomes []models.PublishOutcome) {
+	coll := config.GetDB().Collection("publish_tasks")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": taskID}, bson.M{"$set": bson.M{"results": outcomes}})
+	if err != nil {
+		log.Printf("保存发布结果失败: %v", err)
+	}
 }
 
 // updatePublishTaskStatus 更新发布任务状态
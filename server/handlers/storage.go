@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"strconv"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"newshub/config"
 	"newshub/services"
 )
 
@@ -28,9 +35,29 @@ type UploadImageRequest struct {
 
 // UploadImage 上传图片
 func (h *StorageHandler) UploadImage(c *gin.Context) {
+	h.uploadMedia(c, "images", config.GetMaxImageBytes(), isImageType, "只支持图片文件")
+}
+
+// UploadVideo 上传视频
+func (h *StorageHandler) UploadVideo(c *gin.Context) {
+	h.uploadMedia(c, "videos", config.GetMaxVideoBytes(), isVideoType, "只支持视频文件")
+}
+
+// uploadMedia 校验大小与文件类型后上传到MinIO
+// 文件类型根据文件头部内容判断，不信任客户端声明的Content-Type
+func (h *StorageHandler) uploadMedia(c *gin.Context, defaultFolder string, maxBytes int64, allowed func(string) bool, typeError string) {
+	// 限制请求体大小，避免超大文件在解析表单时写满临时目录（预留1MB给表单的其他部分）
+	if maxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+	}
+
 	// 解析表单数据
 	var req UploadImageRequest
 	if err := c.ShouldBind(&req); err != nil {
+		if isRequestTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过%d字节", maxBytes)})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
@@ -38,26 +65,40 @@ func (h *StorageHandler) UploadImage(c *gin.Context) {
 	// 获取上传的文件
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
+		if isRequestTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过%d字节", maxBytes)})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "获取文件失败"})
 		return
 	}
 	defer file.Close()
 
+	if maxBytes > 0 && header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过%d字节", maxBytes)})
+		return
+	}
+
 	// 验证文件类型
-	contentType := header.Header.Get("Content-Type")
-	if !isImageType(contentType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "只支持图片文件"})
+	contentType, err := detectUploadContentType(file, header.Header.Get("Content-Type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+	if !allowed(contentType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": typeError})
 		return
 	}
 
-	// 设置默认文件夹
-	folder := req.Folder
-	if folder == "" {
-		folder = "images"
+	// 校验并规范化文件夹
+	folder, err := resolveUploadFolder(req.Folder, defaultFolder)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// 上传文件
-	fileInfo, err := h.storageService.UploadFile(c.Request.Context(), file, header, folder)
+	fileInfo, err := h.storageService.UploadFile(c.Request.Context(), file, header, folder, contentType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -69,59 +110,172 @@ func (h *StorageHandler) UploadImage(c *gin.Context) {
 	})
 }
 
-// UploadVideo 上传视频
-func (h *StorageHandler) UploadVideo(c *gin.Context) {
-	// 解析表单数据
-	var req UploadImageRequest
-	if err := c.ShouldBind(&req); err != nil {
+// unsniffableTypes http.DetectContentType 无法识别的格式，嗅探结果为通用类型时沿用客户端声明的类型
+var unsniffableTypes = map[string]bool{
+	"video/flv": true,
+	"video/wmv": true,
+}
+
+// ftypBrands ISO基础媒体格式中 http.DetectContentType 不识别的主品牌（ftyp盒），按前缀匹配
+var ftypBrands = []struct {
+	prefix      string
+	contentType string
+}{
+	{"qt  ", "video/mov"},
+	{"3gp", "video/3gp"},
+	{"3g2", "video/3gp"},
+}
+
+// sniffFtypBrand 按ftyp盒的主品牌识别MOV、3GP等容器，无法识别时返回空字符串
+func sniffFtypBrand(head []byte) string {
+	if len(head) < 12 || string(head[4:8]) != "ftyp" {
+		return ""
+	}
+	major := string(head[8:12])
+	for _, brand := range ftypBrands {
+		if strings.HasPrefix(major, brand.prefix) {
+			return brand.contentType
+		}
+	}
+	return ""
+}
+
+// detectUploadContentType 根据文件前512字节判断文件类型，读取后将文件指针移回开头
+func detectUploadContentType(file multipart.File, declared string) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	sniffed := http.DetectContentType(head[:n])
+	if mediaType, _, err := mime.ParseMediaType(sniffed); err == nil {
+		sniffed = mediaType
+	}
+	switch sniffed {
+	case "application/octet-stream", "text/plain", "text/xml":
+		if brandType := sniffFtypBrand(head[:n]); brandType != "" {
+			return brandType, nil
+		}
+		if unsniffableTypes[declared] {
+			return declared, nil
+		}
+	}
+	return sniffed, nil
+}
+
+// isRequestTooLarge 请求体是否超过了 http.MaxBytesReader 的限制
+func isRequestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// 预签名上传URL的有效期
+const (
+	defaultUploadURLExpiry = 15 * time.Minute
+	maxUploadURLExpiry     = 24 * time.Hour
+)
+
+// uploadExtensions 各上传类型允许的文件扩展名，预签名上传的对象名沿用原文件的扩展名
+var uploadExtensions = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/jpg":  {".jpg", ".jpeg"},
+	"image/png":  {".png"},
+	"image/gif":  {".gif"},
+	"image/webp": {".webp"},
+	"image/bmp":  {".bmp"},
+	"video/mp4":  {".mp4"},
+	"video/avi":  {".avi"},
+	"video/mov":  {".mov"},
+	"video/wmv":  {".wmv"},
+	"video/flv":  {".flv"},
+	"video/webm": {".webm"},
+	"video/mkv":  {".mkv"},
+	"video/3gp":  {".3gp", ".3g2"},
+}
+
+// isAllowedUploadExtension 文件扩展名是否与声明的类型匹配
+func isAllowedUploadExtension(filename, contentType string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	for _, allowed := range uploadExtensions[contentType] {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUploadURL 生成预签名的POST上传策略，客户端直接上传到MinIO，适合大视频文件
+// 策略限定了Content-Type与文件大小（MAX_IMAGE_BYTES / MAX_VIDEO_BYTES）；
+// 直传的文件不经过服务端，不做内容嗅探，也不会记录哈希参与上传去重
+func (h *StorageHandler) GetUploadURL(c *gin.Context) {
+	var req struct {
+		Folder      string `json:"folder"`
+		Filename    string `json:"filename" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+		Expiry      string `json:"expiry"` // 有效期，如 30m，默认15分钟，最长24小时
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
 
-	// 获取上传的文件
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "获取文件失败"})
+	// 按文件类型决定默认文件夹与大小上限
+	var (
+		defaultFolder string
+		maxBytes      int64
+	)
+	switch {
+	case isImageType(req.ContentType):
+		defaultFolder, maxBytes = "images", config.GetMaxImageBytes()
+	case isVideoType(req.ContentType):
+		defaultFolder, maxBytes = "videos", config.GetMaxVideoBytes()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只支持图片或视频文件"})
+		return
+	}
+	if !isAllowedUploadExtension(req.Filename, req.ContentType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件扩展名与content_type不匹配"})
 		return
 	}
-	defer file.Close()
 
-	// 验证文件类型
-	contentType := header.Header.Get("Content-Type")
-	if !isVideoType(contentType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "只支持视频文件"})
+	folder, err := resolveUploadFolder(req.Folder, defaultFolder)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 设置默认文件夹
-	folder := req.Folder
-	if folder == "" {
-		folder = "videos"
+	expiry := defaultUploadURLExpiry
+	if req.Expiry != "" {
+		duration, err := time.ParseDuration(req.Expiry)
+		if err != nil || duration <= 0 || duration > maxUploadURLExpiry {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiry 必须是不超过24h的有效时长"})
+			return
+		}
+		expiry = duration
 	}
 
-	// 上传文件
-	fileInfo, err := h.storageService.UploadFile(c.Request.Context(), file, header, folder)
+	upload, err := h.storageService.GeneratePresignedPost(c.Request.Context(), folder, req.Filename, req.ContentType, maxBytes, expiry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "文件上传成功",
-		"data":    fileInfo,
+		"message": "生成上传URL成功",
+		"data":    upload,
 	})
 }
 
 // ListFiles 列出文件
 func (h *StorageHandler) ListFiles(c *gin.Context) {
 	folder := c.Query("folder")
-	limitStr := c.DefaultQuery("limit", "20")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		limit = 20
-	}
+	pagination := parsePagination(c, "files")
 
-	files, err := h.storageService.ListFiles(c.Request.Context(), folder, limit)
+	files, err := h.storageService.ListFiles(c.Request.Context(), folder, int(pagination.Limit))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -182,6 +336,7 @@ func (h *StorageHandler) GetFileURL(c *gin.Context) {
 }
 
 // isImageType 检查是否为图片类型
+// 不接受SVG：SVG可以内嵌脚本，通过文件URL直接访问时存在XSS风险
 func isImageType(contentType string) bool {
 	imageTypes := []string{
 		"image/jpeg",
@@ -190,7 +345,6 @@ func isImageType(contentType string) bool {
 		"image/gif",
 		"image/webp",
 		"image/bmp",
-		"image/svg+xml",
 	}
 
 	for _, imageType := range imageTypes {
@@ -220,4 +374,27 @@ func isVideoType(contentType string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// resolveUploadFolder 规范化上传文件夹，只允许配置中的顶级文件夹及其子目录
+func resolveUploadFolder(folder, defaultFolder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if folder == "" {
+		folder = defaultFolder
+	}
+
+	for _, segment := range strings.Split(strings.ReplaceAll(folder, "\\", "/"), "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("无效的文件夹: %s", folder)
+		}
+	}
+	folder = strings.Trim(path.Clean("/"+folder), "/")
+
+	root := strings.SplitN(folder, "/", 2)[0]
+	for _, allowed := range config.GetAllowedUploadFolders() {
+		if root == allowed {
+			return folder, nil
+		}
+	}
+	return "", fmt.Errorf("不允许上传到文件夹: %s", folder)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// memoryFile 以内存数据实现 multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+func newMemoryFile(data []byte) memoryFile {
+	return memoryFile{bytes.NewReader(data)}
+}
+
+// ftypHeader 构造以指定主品牌开头的ftyp盒
+func ftypHeader(major string, compatible ...string) []byte {
+	body := []byte("ftyp" + major + "\x00\x00\x00\x00")
+	for _, brand := range compatible {
+		body = append(body, brand...)
+	}
+	size := len(body) + 4
+	return append([]byte{0, 0, 0, byte(size)}, body...)
+}
+
+func TestDetectUploadContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	svg := []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		declared string
+		want     string
+	}{
+		{"PNG按内容识别", png, "image/png", "image/png"},
+		{"声明类型与内容不符时以内容为准", png, "video/mp4", "image/png"},
+		{"MP4", ftypHeader("isom", "isom", "mp41"), "video/mp4", "video/mp4"},
+		{"MOV按ftyp主品牌识别", ftypHeader("qt  ", "qt  "), "video/quicktime", "video/mov"},
+		{"3GP按ftyp主品牌识别", ftypHeader("3gp4", "isom", "3gp4"), "video/3gp", "video/3gp"},
+		{"3G2按ftyp主品牌识别", ftypHeader("3g2a", "3g2a"), "video/3gp", "video/3gp"},
+		{"无法嗅探的FLV沿用声明类型", []byte("\x00\x01\x02\x03binary"), "video/flv", "video/flv"},
+		{"无法嗅探时不信任其他声明类型", []byte("\x00\x01\x02\x03binary"), "image/png", "application/octet-stream"},
+		{"SVG不沿用声明类型", svg, "image/svg+xml", "text/xml"},
+		{"空文件", nil, "image/png", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := newMemoryFile(tt.data)
+			got, err := detectUploadContentType(file, tt.declared)
+			if err != nil {
+				t.Fatalf("detectUploadContentType 返回错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectUploadContentType() = %q，期望 %q", got, tt.want)
+			}
+
+			// 嗅探后文件指针应回到开头，保证完整上传
+			rest, _ := io.ReadAll(file)
+			if !bytes.Equal(rest, tt.data) {
+				t.Error("嗅探后文件指针未回到开头")
+			}
+		})
+	}
+}
+
+func TestUploadTypesRejectSVG(t *testing.T) {
+	if isImageType("image/svg+xml") {
+		t.Error("不应允许上传SVG")
+	}
+	for _, contentType := range []string{"video/mov", "video/3gp"} {
+		if !isVideoType(contentType) {
+			t.Errorf("%s 应为允许的视频类型", contentType)
+		}
+	}
+}
+
+func TestIsAllowedUploadExtension(t *testing.T) {
+	tests := []struct {
+		filename    string
+		contentType string
+		want        bool
+	}{
+		{"photo.JPG", "image/jpeg", true},
+		{"photo.jpeg", "image/jpeg", true},
+		{"clip.mov", "video/mov", true},
+		{"clip.3gp", "video/3gp", true},
+		{"page.html", "image/png", false},
+		{"image.svg", "image/png", false},
+		{"clip.mp4", "image/png", false},
+		{"noext", "video/mp4", false},
+		{"photo.png", "image/svg+xml", false},
+	}
+
+	for _, tt := range tests {
+		if got := isAllowedUploadExtension(tt.filename, tt.contentType); got != tt.want {
+			t.Errorf("isAllowedUploadExtension(%q, %q) = %v，期望 %v", tt.filename, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestResolveUploadFolder(t *testing.T) {
+	t.Setenv("STORAGE_ALLOWED_FOLDERS", "images,videos,posters")
+
+	tests := []struct {
+		name          string
+		folder        string
+		defaultFolder string
+		want          string
+		wantErr       bool
+	}{
+		{"为空时使用默认文件夹", "", "images", "images", false},
+		{"只有空白时使用默认文件夹", "  ", "videos", "videos", false},
+		{"允许的顶级文件夹", "posters", "images", "posters", false},
+		{"允许的子目录", "images/2024/01", "images", "images/2024/01", false},
+		{"去除首尾斜杠与重复斜杠", "/images//avatars/", "images", "images/avatars", false},
+		{"反斜杠不作为目录分隔符", "images\\avatars", "images", "", true},
+		{"当前目录段被清理", "images/./avatars", "images", "images/avatars", false},
+		{"不允许的顶级文件夹", "documents", "images", "", true},
+		{"前缀相同但不是允许的文件夹", "imagesx/a", "images", "", true},
+		{"拒绝上级目录", "images/../secrets", "images", "", true},
+		{"拒绝反斜杠形式的上级目录", "images\\..\\secrets", "images", "", true},
+		{"拒绝以上级目录开头", "../images", "images", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveUploadFolder(tt.folder, tt.defaultFolder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveUploadFolder(%q) 错误 = %v，期望出错 %v", tt.folder, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveUploadFolder(%q) = %q，期望 %q", tt.folder, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("按配置限制顶级文件夹", func(t *testing.T) {
+		t.Setenv("STORAGE_ALLOWED_FOLDERS", " avatars/ ")
+		if _, err := resolveUploadFolder("images", "images"); err == nil {
+			t.Error("未配置的文件夹应被拒绝")
+		}
+		if got, err := resolveUploadFolder("avatars/u1", "images"); err != nil || got != "avatars/u1" {
+			t.Errorf("resolveUploadFolder(\"avatars/u1\") = %q, %v", got, err)
+		}
+	})
+}
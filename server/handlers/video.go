@@ -2,10 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"io"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +15,13 @@ import (
 
 	"newshub/config"
 	"newshub/models"
+	"newshub/services"
+)
+
+// 视频时长范围（秒）
+const (
+	minVideoDuration = 1
+	maxVideoDuration = 60
 )
 
 // GenerateVideo 生成视频
@@ -24,6 +32,11 @@ func GenerateVideo(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
+	// 未传时长（0）时沿用默认值，传了则必须在允许范围内
+	if video.Duration != 0 && (video.Duration < minVideoDuration || video.Duration > maxVideoDuration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频时长必须在1到60秒之间"})
+		return
+	}
 
 	// 设置视频ID和创建时间
 	video.ID = primitive.NewObjectID()
@@ -84,37 +97,37 @@ func GetVideos(c *gin.Context) {
 func GetVideo(c *gin.Context) {
 	videoID := c.Param("id")
 
-	// 检查视频文件是否存在
-	videoPath := config.GetVideoPath(videoID)
-	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "视频文件不存在"})
-		return
+	// 查询视频记录中的MinIO对象名（记录不存在时按默认路径查找）
+	var objectName string
+	if objID, err := primitive.ObjectIDFromHex(videoID); err == nil {
+		if video, err := getVideoInfo(objID); err == nil {
+			objectName = video.ObjectName
+		}
 	}
 
-	// 获取文件信息
-	fileInfo, err := os.Stat(videoPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取视频文件信息失败"})
-		return
-	}
-
-	// 打开文件
-	file, err := os.Open(videoPath)
+	// 视频可能存放在本地磁盘或MinIO
+	source, err := services.NewStorageService().OpenVideo(c.Request.Context(), videoID, objectName)
 	if err != nil {
+		if errors.Is(err, services.ErrVideoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "视频文件不存在"})
+			return
+		}
+		log.Printf("打开视频文件失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "打开视频文件失败"})
 		return
 	}
-	defer file.Close()
+	defer source.Close()
 
 	// 设置响应头
-	c.Header("Content-Type", "video/mp4")
-	c.Header("Content-Length", string(fileInfo.Size()))
-	c.Header("Content-Disposition", "inline; filename=\""+filepath.Base(videoPath)+"\"")
+	c.Header("Content-Type", source.ContentType)
+	if source.Size >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(source.Size, 10))
+	}
+	c.Header("Content-Disposition", "inline; filename=\""+source.Name+"\"")
 
-	// 发送文件内容
-	if _, err := io.Copy(c.Writer, file); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送视频文件失败"})
-		return
+	// 发送文件内容，响应头已写出，失败时只能记录日志
+	if _, err := io.Copy(c.Writer, source); err != nil {
+		log.Printf("发送视频文件失败: %v", err)
 	}
 }
 
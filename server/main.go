@@ -8,12 +8,14 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 
 	"context"
 	"newshub/config"
 	"newshub/crawler"
 	"newshub/handlers"
 	"newshub/middleware"
+	"newshub/migrations"
 	"newshub/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -26,11 +28,23 @@ func main() {
 		log.Printf("警告：未找到.env文件：%v\n", err)
 	}
 
+	// 内容哈希的标准化步骤，去重与定时爬取共用
+	hashNormalization, err := utils.ParseHashNormalization(config.GetContentHashNormalization())
+	if err != nil {
+		log.Printf("警告：%v，使用精确匹配\n", err)
+	}
+	utils.SetHashNormalization(hashNormalization)
+
 	// 连接数据库
 	if err := config.ConnectDB(); err != nil {
 		log.Fatalf("连接数据库失败：%v\n", err)
 	}
 
+	// 执行尚未应用的数据库迁移
+	if err := migrations.Run(config.GetDB()); err != nil {
+		log.Printf("数据库迁移失败：%v\n", err)
+	}
+
 	// 初始化MinIO客户端
 	if err := config.InitMinIO(); err != nil {
 		log.Fatalf("初始化MinIO失败：%v\n", err)
@@ -65,14 +79,30 @@ func main() {
 	r.Use(middleware.Logger())
 	// 使用Recovery中间件
 	r.Use(gin.Recovery())
-	// 使用限速中间件：每分钟60个请求
-	r.Use(middleware.RateLimit(60, time.Minute))
+	// 使用限速中间件：每分钟60个请求，RATE_LIMIT_BACKEND=redis 时多副本共享限额
+	var redisClient *redis.Client
+	if config.GetRateLimitBackend() == config.RateLimitBackendRedis {
+		client, err := config.NewRedisClient()
+		if err != nil {
+			log.Printf("警告：连接Redis失败，使用进程内限速：%v\n", err)
+		} else {
+			redisClient = client
+			log.Println("✅ 使用Redis限速")
+		}
+	}
+	if redisClient != nil {
+		r.Use(middleware.RateLimitRedis(redisClient, 60, time.Minute))
+	} else {
+		r.Use(middleware.RateLimit(60, time.Minute))
+	}
 	// 使用监控中间件
 	r.Use(middleware.Monitor())
 
-	// 配置CORS
+	// 配置CORS与安全响应头
+	securityConfig := config.GetSecurityConfig()
+	r.Use(middleware.SecurityHeaders(securityConfig))
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:3002"},
+		AllowOrigins:     securityConfig.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		AllowCredentials: true,
@@ -82,6 +112,10 @@ func main() {
 	r.GET("/health", handlers.HealthCheck)
 	// 系统指标路由
 	r.GET("/metrics", middleware.GetMetrics())
+	r.GET("/metrics/prometheus", middleware.GetPrometheusMetrics())
+
+	// 定期将请求指标按小时持久化
+	middleware.StartMetricsSnapshot(config.GetDB(), config.GetEnvDuration("METRICS_SNAPSHOT_INTERVAL", time.Minute))
 
 	// 创建存储处理器
 	storageHandler := handlers.NewStorageHandler()
@@ -92,7 +126,12 @@ func main() {
 		// 创作者相关接口
 		api.POST("/creators", handlers.CreateCreator)
 		api.GET("/creators", handlers.GetCreators)
+		api.POST("/creators/auto-crawl", handlers.BulkSetCreatorAutoCrawl)
+		api.PUT("/creators/:id", handlers.UpdateCreator)
 		api.DELETE("/creators/:id", handlers.DeleteCreator)
+		api.GET("/creators/:id/error", handlers.GetCreatorError)
+		api.POST("/creators/:id/error/clear", handlers.ClearCreatorError)
+		api.POST("/creators/:id/crawl-now", handlers.CrawlCreatorNow(crawlerService))
 
 		// 视频相关接口
 		api.POST("/videos/generate", handlers.GenerateVideo)
@@ -108,31 +147,53 @@ func main() {
 
 		// 帖子相关接口
 		api.GET("/posts", handlers.GetPosts)
+		api.POST("/posts/materialize", middleware.RequireAdminToken(), handlers.MaterializePosts)
 		api.GET("/posts/:id", handlers.GetPost)
 		api.DELETE("/posts/:id", handlers.DeletePost)
 
 		// 存储相关接口
 		api.POST("/storage/upload/image", storageHandler.UploadImage)
 		api.POST("/storage/upload/video", storageHandler.UploadVideo)
+		api.POST("/storage/upload-url", storageHandler.GetUploadURL)
 		api.GET("/storage/files", storageHandler.ListFiles)
 		api.GET("/storage/files/:filename/url", storageHandler.GetFileURL)
 		api.DELETE("/storage/files/*filename", storageHandler.DeleteFile)
 
+		// 历史请求指标
+		api.GET("/metrics/history", middleware.GetMetricsHistory(config.GetDB()))
+		// 实时日志（SSE，需配置ADMIN_TOKEN）
+		api.GET("/logs/tail", middleware.RequireAdminToken(), middleware.TailLogs())
+
 		// 爬虫服务代理接口 (转发到Python服务)
 		api.POST("/crawler/trigger", handlers.ProxyCrawlerTrigger)
+		api.POST("/crawler/test", handlers.TestCrawl)
 		api.GET("/crawler/status", handlers.ProxyCrawlerStatus)
 		api.GET("/crawler/platforms", handlers.GetCrawlerPlatforms)
 
 		// 爬取任务管理接口
 		api.POST("/crawler/tasks", handlers.CreateCrawlerTask)
 		api.GET("/crawler/tasks", handlers.GetCrawlerTasks)
+		api.GET("/crawler/tasks/stats", handlers.GetCrawlerTaskStats)
 		api.GET("/crawler/tasks/:id", handlers.GetCrawlerTask)
+		api.GET("/crawler/tasks/:id/raw", handlers.GetCrawlerTaskRaw)
 		api.PUT("/crawler/tasks/:id/status", handlers.UpdateCrawlerTaskStatus)
 		api.DELETE("/crawler/tasks/:id", handlers.DeleteCrawlerTask)
 		api.DELETE("/crawler/tasks", handlers.BatchDeleteCrawlerTasks)
 
 		// 爬取内容接口
 		api.GET("/crawler/contents", handlers.GetCrawlerContents)
+		api.DELETE("/crawler/contents", middleware.RequireAdminToken(), handlers.DeleteCrawlerContents)
+		api.GET("/crawler/contents/stream", handlers.StreamCrawlerContents)
+		api.GET("/crawler/contents/:id/similar", handlers.GetSimilarContents)
+		api.GET("/crawler/contents/:id/full", handlers.GetCrawlerContentFull)
+
+		// 去重统计
+		api.GET("/deduplication/stats", handlers.GetDeduplicationStats)
+		api.POST("/deduplication/reprocess", middleware.RequireAdminToken(), handlers.ReprocessDeduplication)
+		api.GET("/deduplication/reprocess/status", middleware.RequireAdminToken(), handlers.GetReprocessDeduplicationStatus)
+
+		// 爬取健康度
+		api.GET("/analytics/crawl-health", handlers.GetCrawlHealth)
 	}
 
 	// 加载配置文件
@@ -161,8 +222,25 @@ func main() {
 		}
 	}()
 
-	// 等待中断信号以优雅地关闭服务器
-	utils.GracefulShutdown(srv)
+	// 等待中断信号以优雅地关闭服务器：先排空HTTP请求，再依次停止后台服务
+	utils.GracefulShutdown(srv, config.GetEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		utils.ShutdownHook{Name: "定时爬虫服务", Stop: func(ctx context.Context) error {
+			crawlerService.Stop()
+			return nil
+		}},
+		utils.ShutdownHook{Name: "请求指标快照", Stop: func(ctx context.Context) error {
+			return middleware.FlushMetricsSnapshot(config.GetDB())
+		}},
+		utils.ShutdownHook{Name: "Redis连接", Stop: func(ctx context.Context) error {
+			if redisClient == nil {
+				return nil
+			}
+			return redisClient.Close()
+		}},
+		utils.ShutdownHook{Name: "数据库连接", Stop: func(ctx context.Context) error {
+			return config.GetDB().Client().Disconnect(ctx)
+		}},
+	)
 }
 
 // seedCreatorsIfEmpty 如果 creators 集合为空，写入示例创作者数据
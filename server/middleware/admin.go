@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdminToken 保护管理类接口：需设置环境变量 ADMIN_TOKEN，请求通过 X-Admin-Token 头携带该值
+// 未设置 ADMIN_TOKEN 时接口不可用
+func RequireAdminToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "管理接口未启用"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "无权访问管理接口"})
+			return
+		}
+		c.Next()
+	}
+}
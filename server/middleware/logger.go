@@ -1,16 +1,36 @@
 package middleware
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"newshub/config"
 )
 
+// requestLogEntry LOG_FORMAT=json 时每行写入的请求日志
+type requestLogEntry struct {
+	Timestamp string  `json:"timestamp"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	Bytes     int     `json:"bytes"`
+	UserID    string  `json:"user_id,omitempty"`
+}
+
 // Logger 中间件用于记录API请求日志
+// 环境变量 LOG_FORMAT=json 时每行输出一个JSON对象，默认为文本格式
 func Logger() gin.HandlerFunc {
+	jsonFormat := os.Getenv("LOG_FORMAT") == "json"
+
 	// 确保日志目录存在
 	logDir := "logs"
 	if err := os.MkdirAll(logDir, 0755); err != nil {
@@ -18,10 +38,9 @@ func Logger() gin.HandlerFunc {
 		return nil
 	}
 
-	// 创建或打开日志文件
-	logFile := filepath.Join(logDir, time.Now().Format("2006-01-02")+".log")
-	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	// 创建或打开当天的日志文件，跨天时自动切换
+	f := &dailyLogFile{dir: logDir, retentionDays: config.GetEnvInt("LOG_RETENTION_DAYS", 14)}
+	if err := f.rotate(time.Now()); err != nil {
 		fmt.Printf("打开日志文件失败：%v\n", err)
 		return nil
 	}
@@ -47,18 +66,112 @@ func Logger() gin.HandlerFunc {
 		clientIP := c.ClientIP()
 
 		// 日志格式
-		logStr := fmt.Sprintf("[%s] %s | %3d | %13v | %15s | %s\n",
-			endTime.Format("2006-01-02 15:04:05"),
-			reqMethod,
-			statusCode,
-			latencyTime,
-			clientIP,
-			reqUri,
-		)
+		var logStr string
+		if jsonFormat {
+			bytes := c.Writer.Size()
+			if bytes < 0 {
+				bytes = 0
+			}
+			data, err := json.Marshal(requestLogEntry{
+				Timestamp: endTime.Format(time.RFC3339Nano),
+				Method:    reqMethod,
+				Path:      reqUri,
+				Status:    statusCode,
+				LatencyMs: float64(latencyTime.Microseconds()) / 1000,
+				ClientIP:  clientIP,
+				Bytes:     bytes,
+				UserID:    c.GetString("user_id"),
+			})
+			if err != nil {
+				fmt.Printf("序列化日志失败：%v\n", err)
+				return
+			}
+			logStr = string(data) + "\n"
+		} else {
+			logStr = fmt.Sprintf("[%s] %s | %3d | %13v | %15s | %s\n",
+				endTime.Format("2006-01-02 15:04:05"),
+				reqMethod,
+				statusCode,
+				latencyTime,
+				clientIP,
+				reqUri,
+			)
+		}
 
 		// 写入日志文件
-		if _, err := f.WriteString(logStr); err != nil {
+		if err := f.WriteString(endTime, logStr); err != nil {
 			fmt.Printf("写入日志失败：%v\n", err)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// logFileDateLayout 日志文件名中的日期格式
+const logFileDateLayout = "2006-01-02"
+
+// dailyLogFile 按天切换的日志文件，并删除超过保留天数的旧文件
+type dailyLogFile struct {
+	dir           string
+	retentionDays int // 小于等于0时不删除旧日志
+
+	mutex sync.Mutex
+	day   string
+	file  *os.File
+}
+
+// WriteString 写入一行日志，日期变化时先切换到新文件
+func (l *dailyLogFile) WriteString(now time.Time, s string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Format(logFileDateLayout) != l.day {
+		if err := l.rotate(now); err != nil {
+			return err
+		}
+	}
+	_, err := l.file.WriteString(s)
+	return err
+}
+
+// rotate 打开当天的日志文件并关闭旧文件，调用方需持有锁（初始化时除外）
+func (l *dailyLogFile) rotate(now time.Time) error {
+	day := now.Format(logFileDateLayout)
+	f, err := os.OpenFile(filepath.Join(l.dir, day+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file, l.day = f, day
+
+	l.removeExpired(now)
+	return nil
+}
+
+// removeExpired 删除日期早于保留期的日志文件
+func (l *dailyLogFile) removeExpired(now time.Time) {
+	if l.retentionDays <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		fmt.Printf("读取日志目录失败：%v\n", err)
+		return
+	}
+
+	cutoff := now.AddDate(0, 0, -l.retentionDays).Format(logFileDateLayout)
+	for _, entry := range entries {
+		name := entry.Name()
+		day := strings.TrimSuffix(name, ".log")
+		if entry.IsDir() || day == name {
+			continue
+		}
+		// 只处理按日期命名的文件，日期字符串可直接按字典序比较
+		if _, err := time.Parse(logFileDateLayout, day); err != nil || day >= cutoff {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.dir, name)); err != nil {
+			fmt.Printf("删除过期日志失败：%v\n", err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logTailPollInterval = 500 * time.Millisecond
+	logTailBufferSize   = 256
+)
+
+// TailLogs 以SSE方式实时推送当天请求日志的新增行
+// 需配合 RequireAdminToken 使用，令牌只通过请求头传递，避免出现在请求日志中
+// level 参数：error 只推送5xx，warn 推送4xx及以上，默认推送全部
+func TailLogs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		minStatus := 0
+		switch c.DefaultQuery("level", "info") {
+		case "error":
+			minStatus = 500
+		case "warn":
+			minStatus = 400
+		}
+
+		// 读取文件与写出响应分离：客户端过慢时丢弃缓冲区外的行，不影响日志写入
+		lines := make(chan string, logTailBufferSize)
+		var dropped int64
+		ctx := c.Request.Context()
+		go followLogFile(ctx.Done(), lines, &dropped)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case line, ok := <-lines:
+				if !ok {
+					return false
+				}
+				if n := atomic.SwapInt64(&dropped, 0); n > 0 {
+					c.SSEvent("dropped", gin.H{"count": n})
+				}
+				if logLineStatus(line) >= minStatus {
+					c.SSEvent("log", line)
+				}
+			case <-heartbeat.C:
+				c.SSEvent("ping", time.Now().Unix())
+			}
+			return true
+		})
+	}
+}
+
+// followLogFile 从当天日志文件末尾开始轮询新增行，跨天时切换到新文件
+func followLogFile(done <-chan struct{}, lines chan<- string, dropped *int64) {
+	defer close(lines)
+
+	var (
+		file    *os.File
+		reader  *bufio.Reader
+		current string
+		partial string
+	)
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(logTailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		path := filepath.Join("logs", time.Now().Format("2006-01-02")+".log")
+		if path != current {
+			if f, err := os.Open(path); err == nil {
+				// 首次打开从末尾开始，跨天切换时从头读取新文件
+				if current == "" {
+					f.Seek(0, io.SeekEnd)
+				}
+				if file != nil {
+					file.Close()
+				}
+				file, reader, current, partial = f, bufio.NewReader(f), path, ""
+			}
+		}
+
+		if reader != nil {
+			for {
+				chunk, err := reader.ReadString('\n')
+				if err != nil {
+					// 行尚未写完，留到下次拼接
+					partial += chunk
+					break
+				}
+				line := strings.TrimRight(partial+chunk, "\r\n")
+				partial = ""
+				select {
+				case lines <- line:
+				default:
+					atomic.AddInt64(dropped, 1)
+				}
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logLineStatus 从Logger写入的日志行（文本或JSON格式）中解析状态码，解析失败返回0
+func logLineStatus(line string) int {
+	if strings.HasPrefix(line, "{") {
+		var entry requestLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return 0
+		}
+		return entry.Status
+	}
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 {
+		return 0
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0
+	}
+	return status
+}
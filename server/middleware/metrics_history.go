@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const metricsHistoryCollection = "http_metrics"
+
+// defaultMetricsSnapshotInterval 快照间隔配置无效时使用的默认间隔
+const defaultMetricsSnapshotInterval = time.Minute
+
+// endpointWindow 单个端点在快照窗口内的统计
+type endpointWindow struct {
+	requests     int64
+	errors       int64
+	latencySumMs float64
+}
+
+// metricsWindow 两次快照之间累计的请求指标
+type metricsWindow struct {
+	requests     int64
+	errors       int64
+	latencySumMs float64
+	latencyMaxMs float64
+	latenciesMs  []float64
+	endpoints    map[string]*endpointWindow
+}
+
+var (
+	window      = newMetricsWindow()
+	windowMutex sync.Mutex
+)
+
+func newMetricsWindow() *metricsWindow {
+	return &metricsWindow{endpoints: make(map[string]*endpointWindow)}
+}
+
+// recordWindow 记录一次请求到当前快照窗口
+func recordWindow(endpoint string, status int, responseTime float64) {
+	latencyMs := responseTime * 1000
+
+	windowMutex.Lock()
+	defer windowMutex.Unlock()
+
+	window.requests++
+	window.latencySumMs += latencyMs
+	if latencyMs > window.latencyMaxMs {
+		window.latencyMaxMs = latencyMs
+	}
+	// 单个窗口最多保留10000个样本用于计算分位数
+	if len(window.latenciesMs) < 10000 {
+		window.latenciesMs = append(window.latenciesMs, latencyMs)
+	}
+
+	ep, ok := window.endpoints[endpoint]
+	if !ok {
+		ep = &endpointWindow{}
+		window.endpoints[endpoint] = ep
+	}
+	ep.requests++
+	ep.latencySumMs += latencyMs
+	if status >= 400 {
+		window.errors++
+		ep.errors++
+	}
+}
+
+// StartMetricsSnapshot 定期将请求指标按小时聚合写入 http_metrics 集合
+// interval 小于等于0时使用默认间隔（1分钟）
+func StartMetricsSnapshot(db *mongo.Database, interval time.Duration) {
+	if interval <= 0 {
+		log.Printf("指标快照间隔 %v 无效，使用默认值 %v", interval, defaultMetricsSnapshotInterval)
+		interval = defaultMetricsSnapshotInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := FlushMetricsSnapshot(db); err != nil {
+				log.Printf("保存请求指标快照失败: %v", err)
+			}
+		}
+	}()
+}
+
+// FlushMetricsSnapshot 将当前窗口的指标累加到所属小时的文档中
+func FlushMetricsSnapshot(db *mongo.Database) error {
+	windowMutex.Lock()
+	current := window
+	window = newMetricsWindow()
+	windowMutex.Unlock()
+
+	if current.requests == 0 {
+		return nil
+	}
+
+	hour := time.Now().Truncate(time.Hour)
+	inc := bson.M{
+		"requests":       current.requests,
+		"errors":         current.errors,
+		"latency_sum_ms": current.latencySumMs,
+	}
+	for endpoint, ep := range current.endpoints {
+		prefix := "endpoints." + sanitizeMetricsKey(endpoint)
+		inc[prefix+".requests"] = ep.requests
+		inc[prefix+".errors"] = ep.errors
+		inc[prefix+".latency_sum_ms"] = ep.latencySumMs
+	}
+
+	update := bson.M{
+		"$inc": inc,
+		// 小时内的分位数取各快照窗口分位数的最大值（近似值）
+		"$max": bson.M{
+			"latency_max_ms": current.latencyMaxMs,
+			"latency_p95_ms": percentile(current.latenciesMs, 95),
+		},
+		"$setOnInsert": bson.M{"hour": hour},
+		"$set":         bson.M{"updated_at": time.Now()},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Collection(metricsHistoryCollection).UpdateOne(ctx,
+		bson.M{"hour": hour},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetMetricsHistory 获取按小时聚合的历史请求指标
+func GetMetricsHistory(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		to := time.Now()
+		from := to.Add(-24 * time.Hour)
+
+		if fromStr := c.Query("from"); fromStr != "" {
+			parsed, err := time.Parse(time.RFC3339, fromStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from 必须是RFC3339格式的时间"})
+				return
+			}
+			from = parsed
+		}
+		if toStr := c.Query("to"); toStr != "" {
+			parsed, err := time.Parse(time.RFC3339, toStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to 必须是RFC3339格式的时间"})
+				return
+			}
+			to = parsed
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		filter := bson.M{"hour": bson.M{"$gte": from.Truncate(time.Hour), "$lte": to}}
+		opts := options.Find().SetSort(bson.D{{Key: "hour", Value: 1}})
+		cursor, err := db.Collection(metricsHistoryCollection).Find(ctx, filter, opts)
+		if err != nil {
+			log.Printf("获取历史请求指标失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取历史请求指标失败"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			log.Printf("解析历史请求指标失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "解析历史请求指标失败"})
+			return
+		}
+
+		buckets := make([]gin.H, 0, len(docs))
+		for _, doc := range docs {
+			requests := toFloat(doc["requests"])
+			bucket := gin.H{
+				"hour":           doc["hour"],
+				"requests":       int64(requests),
+				"errors":         int64(toFloat(doc["errors"])),
+				"avg_latency_ms": safeDiv(toFloat(doc["latency_sum_ms"]), requests),
+				"p95_latency_ms": toFloat(doc["latency_p95_ms"]),
+				"max_latency_ms": toFloat(doc["latency_max_ms"]),
+			}
+
+			endpoints := gin.H{}
+			if eps, ok := doc["endpoints"].(bson.M); ok {
+				for key, value := range eps {
+					ep, ok := value.(bson.M)
+					if !ok {
+						continue
+					}
+					epRequests := toFloat(ep["requests"])
+					endpoints[key] = gin.H{
+						"requests":       int64(epRequests),
+						"errors":         int64(toFloat(ep["errors"])),
+						"avg_latency_ms": safeDiv(toFloat(ep["latency_sum_ms"]), epRequests),
+					}
+				}
+			}
+			bucket["endpoints"] = endpoints
+			buckets = append(buckets, bucket)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":    from,
+			"to":      to,
+			"buckets": buckets,
+			"total":   len(buckets),
+		})
+	}
+}
+
+// sanitizeMetricsKey 将端点标识转换为合法的BSON字段名
+func sanitizeMetricsKey(key string) string {
+	key = strings.ReplaceAll(key, ".", "_")
+	return strings.TrimLeft(key, "$")
+}
+
+// percentile 计算样本的分位数（会对副本排序）
+func percentile(samples []float64, p float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return sortedPercentile(sorted, p)
+}
+
+// sortedPercentile 计算已升序排列的非空样本的分位数
+func sortedPercentile(sorted []float64, p float64) float64 {
+	index := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func safeDiv(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
@@ -1,27 +1,71 @@
 package middleware
 
 import (
+	"os"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"newshub/services"
 )
 
 type Metrics struct {
 	TotalRequests uint64
 	TotalErrors   uint64
 	ResponseTimes []float64
-	mutex         sync.RWMutex
+	PerEndpoint   map[string]uint64 // 按路由统计的请求数，键为 "METHOD /path"
+	// 按路由保存最近的响应时间（秒），每个路由最多 endpointLatencySamples 个
+	PerEndpointLatency map[string][]float64
+	StatusCodes        map[int]uint64 // 按HTTP状态码统计的响应数
+	mutex              sync.RWMutex
 }
 
+// processStartTime 进程启动时间，用于计算运行时长
+var processStartTime = time.Now()
+
+// endpointLatencySamples 每个路由保留的响应时间样本数
+const endpointLatencySamples = 200
+
 var (
 	metrics = &Metrics{
-		ResponseTimes: make([]float64, 0, 1000),
+		ResponseTimes:      make([]float64, 0, 1000),
+		PerEndpoint:        make(map[string]uint64),
+		PerEndpointLatency: make(map[string][]float64),
+		StatusCodes:        make(map[int]uint64),
 	}
 )
 
+// LatencyStats 响应时间分布（秒）
+type LatencyStats struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+}
+
+// computeLatencyStats 复制并排序样本后计算分位数，不修改传入的切片
+func computeLatencyStats(samples []float64) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return LatencyStats{
+		Samples: len(sorted),
+		Min:     sorted[0],
+		Max:     sorted[len(sorted)-1],
+		P50:     sortedPercentile(sorted, 50),
+		P90:     sortedPercentile(sorted, 90),
+		P99:     sortedPercentile(sorted, 99),
+	}
+}
+
 // Monitor 中间件用于收集系统指标
 func Monitor() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -40,16 +84,36 @@ func Monitor() gin.HandlerFunc {
 
 		// 记录响应时间
 		responseTime := time.Since(start).Seconds()
+		endpoint := endpointKey(c)
 		metrics.mutex.Lock()
 		if len(metrics.ResponseTimes) >= 1000 {
 			// 保持最近1000个请求的响应时间
 			metrics.ResponseTimes = metrics.ResponseTimes[1:]
 		}
 		metrics.ResponseTimes = append(metrics.ResponseTimes, responseTime)
+		metrics.PerEndpoint[endpoint]++
+		latencies := metrics.PerEndpointLatency[endpoint]
+		if len(latencies) >= endpointLatencySamples {
+			latencies = latencies[1:]
+		}
+		metrics.PerEndpointLatency[endpoint] = append(latencies, responseTime)
+		metrics.StatusCodes[c.Writer.Status()]++
 		metrics.mutex.Unlock()
+
+		// 累计到待持久化的小时窗口
+		recordWindow(endpoint, c.Writer.Status(), responseTime)
 	}
 }
 
+// endpointKey 以路由模板作为端点标识，避免路径参数导致的基数膨胀
+func endpointKey(c *gin.Context) string {
+	path := c.FullPath()
+	if path == "" {
+		path = "unmatched"
+	}
+	return c.Request.Method + " " + path
+}
+
 // GetMetrics 获取系统指标
 func GetMetrics() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -67,20 +131,43 @@ func GetMetrics() gin.HandlerFunc {
 			}
 			avgResponseTime = sum / float64(len(metrics.ResponseTimes))
 		}
+		latency := computeLatencyStats(metrics.ResponseTimes)
+		endpoints := make(gin.H, len(metrics.PerEndpoint))
+		for endpoint, count := range metrics.PerEndpoint {
+			endpoints[endpoint] = gin.H{
+				"requests": count,
+				"latency":  computeLatencyStats(metrics.PerEndpointLatency[endpoint]),
+			}
+		}
 		metrics.mutex.RUnlock()
 
+		inFlight, waiting, limit := services.PythonDispatchStats()
+		dedupChecks, duplicatesFound := services.GetDeduplicationService().Counters()
+
 		// 返回指标数据
 		c.JSON(200, gin.H{
 			"total_requests":     atomic.LoadUint64(&metrics.TotalRequests),
 			"total_errors":       atomic.LoadUint64(&metrics.TotalErrors),
 			"avg_response_time":  avgResponseTime,
-			"goroutines":        runtime.NumGoroutine(),
+			"latency":            latency,
+			"endpoints":          endpoints,
+			"goroutines":         runtime.NumGoroutine(),
+			"uptime":             time.Since(processStartTime).Seconds(),
+			"go_version":         runtime.Version(),
+			"pid":                os.Getpid(),
+			"dedup_checks_total": dedupChecks,
+			"duplicates_found":   duplicatesFound,
+			"crawler_dispatch": gin.H{
+				"in_flight": inFlight,
+				"waiting":   waiting,
+				"limit":     limit,
+			},
 			"memory": gin.H{
-				"alloc":      memStats.Alloc,
+				"alloc":       memStats.Alloc,
 				"total_alloc": memStats.TotalAlloc,
-				"sys":        memStats.Sys,
-				"num_gc":     memStats.NumGC,
+				"sys":         memStats.Sys,
+				"num_gc":      memStats.NumGC,
 			},
 		})
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"newshub/services"
+)
+
+// prometheusLabelEscaper 转义标签值中的反斜杠、双引号和换行
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// GetPrometheusMetrics 以Prometheus文本格式输出与 /metrics 相同的指标，供Prometheus直接抓取
+func GetPrometheusMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		// 在读锁内复制按端点和状态码的计数，渲染时不再持有锁
+		metrics.mutex.RLock()
+		endpoints := make(map[string]uint64, len(metrics.PerEndpoint))
+		for endpoint, count := range metrics.PerEndpoint {
+			endpoints[endpoint] = count
+		}
+		statusCodes := make(map[int]uint64, len(metrics.StatusCodes))
+		for code, count := range metrics.StatusCodes {
+			statusCodes[code] = count
+		}
+		metrics.mutex.RUnlock()
+
+		inFlight, waiting, limit := services.PythonDispatchStats()
+		dedupChecks, duplicatesFound := services.GetDeduplicationService().Counters()
+
+		var b strings.Builder
+		writeMetric := func(name, kind, help string, value interface{}) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+		}
+
+		writeMetric("newshub_http_requests_total", "counter", "请求总数", atomic.LoadUint64(&metrics.TotalRequests))
+		writeMetric("newshub_http_errors_total", "counter", "状态码>=400的请求数", atomic.LoadUint64(&metrics.TotalErrors))
+
+		b.WriteString("# HELP newshub_http_endpoint_requests_total 按路由统计的请求数\n")
+		b.WriteString("# TYPE newshub_http_endpoint_requests_total counter\n")
+		keys := make([]string, 0, len(endpoints))
+		for endpoint := range endpoints {
+			keys = append(keys, endpoint)
+		}
+		sort.Strings(keys)
+		for _, endpoint := range keys {
+			method, path, _ := strings.Cut(endpoint, " ")
+			fmt.Fprintf(&b, "newshub_http_endpoint_requests_total{method=\"%s\",path=\"%s\"} %d\n",
+				prometheusLabelEscaper.Replace(method), prometheusLabelEscaper.Replace(path), endpoints[endpoint])
+		}
+
+		b.WriteString("# HELP newshub_http_responses_total 按状态码统计的响应数\n")
+		b.WriteString("# TYPE newshub_http_responses_total counter\n")
+		codes := make([]int, 0, len(statusCodes))
+		for code := range statusCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "newshub_http_responses_total{code=\"%s\"} %d\n", strconv.Itoa(code), statusCodes[code])
+		}
+
+		writeMetric("newshub_goroutines", "gauge", "当前goroutine数量", runtime.NumGoroutine())
+		writeMetric("newshub_memory_alloc_bytes", "gauge", "堆上已分配且仍在使用的字节数", memStats.Alloc)
+		writeMetric("newshub_memory_sys_bytes", "gauge", "从系统获取的内存字节数", memStats.Sys)
+		writeMetric("newshub_memory_total_alloc_bytes", "counter", "累计分配的字节数", memStats.TotalAlloc)
+		writeMetric("newshub_gc_total", "counter", "GC次数", memStats.NumGC)
+		writeMetric("newshub_dedup_checks_total", "counter", "去重检查次数", dedupChecks)
+		writeMetric("newshub_duplicates_found_total", "counter", "发现的重复内容数", duplicatesFound)
+		writeMetric("newshub_crawler_dispatch_in_flight", "gauge", "正在执行的Python爬虫请求数", inFlight)
+		writeMetric("newshub_crawler_dispatch_waiting", "gauge", "等待执行的Python爬虫请求数", waiting)
+		writeMetric("newshub_crawler_dispatch_limit", "gauge", "Python爬虫并发上限", limit)
+
+		c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+	}
+}
@@ -21,13 +21,42 @@ type RequestCount struct {
 	blockTil time.Time
 }
 
-// NewRateLimiter 创建一个新的限速器
+// rateLimitSweepWindows 每隔多少个时间窗口清理一次过期的客户端记录
+const rateLimitSweepWindows = 5
+
+// NewRateLimiter 创建一个新的限速器，并启动后台协程定期清理过期记录
 func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
+	limiter := &RateLimiter{
 		rate:     rate,
 		window:   window,
 		requests: make(map[string]*RequestCount),
 	}
+	if window > 0 {
+		go limiter.sweepLoop(window * rateLimitSweepWindows)
+	}
+	return limiter
+}
+
+// sweepLoop 按固定间隔清理过期记录，限速器随进程存活，无需停止
+func (rl *RateLimiter) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		rl.sweep(now)
+	}
+}
+
+// sweep 删除计数窗口已结束且不在封禁期内的客户端记录，避免map无限增长
+func (rl *RateLimiter) sweep(now time.Time) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for key, req := range rl.requests {
+		if now.Sub(req.start) >= rl.window && !now.Before(req.blockTil) {
+			delete(rl.requests, key)
+		}
+	}
 }
 
 // RateLimit 中间件用于限制API请求速率
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRateLimitTimeout 单次限速判断访问Redis的超时时间
+const redisRateLimitTimeout = 200 * time.Millisecond
+
+// rateLimitScript 原子地累加当前窗口的计数，超过限额时写入封禁键
+// 返回值：{是否放行(1/0), 剩余封禁毫秒数}
+var rateLimitScript = redis.NewScript(`
+local blocked = redis.call("PTTL", KEYS[2])
+if blocked > 0 then
+	return {0, blocked}
+end
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if count > tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[2], 1, "PX", ARGV[2])
+	return {0, tonumber(ARGV[2])}
+end
+return {1, 0}
+`)
+
+// RateLimitRedis 基于Redis的限速中间件，多副本共享同一计数
+// 语义与 RateLimit 一致：窗口内超过限额后封禁一个窗口；Redis不可用时放行请求
+func RateLimitRedis(client *redis.Client, rate int, window time.Duration) gin.HandlerFunc {
+	windowMs := strconv.FormatInt(window.Milliseconds(), 10)
+
+	return func(c *gin.Context) {
+		clientIP := c.ClientIP()
+		keys := []string{"ratelimit:count:" + clientIP, "ratelimit:block:" + clientIP}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), redisRateLimitTimeout)
+		result, err := rateLimitScript.Run(ctx, client, keys, rate, windowMs).Int64Slice()
+		cancel()
+		if err != nil || len(result) != 2 {
+			log.Printf("Redis限速检查失败，放行请求: %v", err)
+			c.Next()
+			return
+		}
+
+		if result[0] == 0 {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "请求过于频繁，请稍后再试",
+				"retry_after": time.Duration(result[1] * int64(time.Millisecond)).Seconds(),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
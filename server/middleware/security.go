@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"newshub/config"
+)
+
+// defaultSecurityHeaders 默认附加到所有响应的安全头
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+	"Referrer-Policy":        "strict-origin-when-cross-origin",
+}
+
+// SecurityHeaders 为响应添加安全相关的头部，视频与文件访问路由不设置CSP
+func SecurityHeaders(cfg config.SecurityConfig) gin.HandlerFunc {
+	headers := make(map[string]string, len(defaultSecurityHeaders)+len(cfg.Headers))
+	for key, value := range defaultSecurityHeaders {
+		headers[key] = value
+	}
+	for key, value := range cfg.Headers {
+		if value == "" {
+			delete(headers, key)
+			continue
+		}
+		headers[key] = value
+	}
+
+	return func(c *gin.Context) {
+		for key, value := range headers {
+			c.Header(key, value)
+		}
+
+		if cfg.CSP != "" && cfg.CSP != "-" && !isCSPExempt(c.Request.URL.Path, cfg.CSPExempt) {
+			c.Header("Content-Security-Policy", cfg.CSP)
+		}
+
+		c.Next()
+	}
+}
+
+func isCSPExempt(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,11 +3,12 @@ package middleware
 import (
 	"net/http"
 	"regexp"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+
+	"newshub/config"
 )
 
 // 自定义验证器
@@ -33,13 +34,8 @@ func RegisterCustomValidators() {
 
 		// 注册平台名称验证器
 		_ = v.RegisterValidation("validplatform", func(fl validator.FieldLevel) bool {
-			validPlatforms := map[string]bool{
-				"youtube":    true,
-				"twitter":    true,
-				"instagram": true,
-				"tiktok":    true,
-			}
-			return validPlatforms[strings.ToLower(fl.Field().String())]
+			// 经别名解析后判断，twitter、X、微博等写法均可通过
+			return config.IsKnownPlatform(fl.Field().String())
 		})
 	}
 }
@@ -0,0 +1,323 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration 一次性的数据库结构变更
+// Up 必须是幂等的：即使迁移记录丢失被重复执行，也不能破坏数据
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrationRecord 已应用迁移的记录
+type migrationRecord struct {
+	ID          string    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	DurationMs  int64     `bson:"duration_ms"`
+}
+
+const collectionName = "migrations"
+
+// Run 按顺序执行所有尚未应用的迁移
+// 单个迁移失败不会阻止后续迁移执行（迁移之间互不依赖），失败的迁移在下次启动时重试，所有失败合并后返回
+func Run(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	applied, err := appliedIDs(ctx, db)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("读取迁移记录失败: %v", err)
+	}
+
+	pending := 0
+	var errs []error
+	for _, m := range registry {
+		if applied[m.ID] {
+			continue
+		}
+		pending++
+
+		log.Printf("执行数据库迁移: %s (%s)", m.ID, m.Description)
+		start := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		err := m.Up(ctx, db)
+		if err == nil {
+			_, err = db.Collection(collectionName).InsertOne(ctx, migrationRecord{
+				ID:          m.ID,
+				Description: m.Description,
+				AppliedAt:   time.Now(),
+				DurationMs:  time.Since(start).Milliseconds(),
+			})
+		}
+		cancel()
+
+		if err != nil {
+			log.Printf("数据库迁移失败: %s: %v", m.ID, err)
+			errs = append(errs, fmt.Errorf("迁移 %s 失败: %v", m.ID, err))
+			continue
+		}
+		log.Printf("数据库迁移完成: %s，耗时 %v", m.ID, time.Since(start))
+	}
+
+	if pending == 0 {
+		log.Println("数据库结构已是最新，无需迁移")
+	}
+	return errors.Join(errs...)
+}
+
+// appliedIDs 获取已应用的迁移ID集合
+func appliedIDs(ctx context.Context, db *mongo.Database) (map[string]bool, error) {
+	cursor, err := db.Collection(collectionName).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, r := range records {
+		applied[r.ID] = true
+	}
+	return applied, nil
+}
+
+// createIndexes 创建索引的辅助函数，索引定义相同时重复创建是无操作的
+func createIndexes(ctx context.Context, db *mongo.Database, collection string, indexes []mongo.IndexModel) error {
+	if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("创建%s索引失败: %v", collection, err)
+	}
+	return nil
+}
+
+// isIndexNotFound 判断是否为索引不存在的错误
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 27 || cmdErr.Name == "IndexNotFound"
+	}
+	return false
+}
+
+// dropTTLIndexes 删除集合上的所有TTL索引
+func dropTTLIndexes(ctx context.Context, db *mongo.Database, collection string) error {
+	specs, err := db.Collection(collection).Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("读取%s索引失败: %v", collection, err)
+	}
+	for _, spec := range specs {
+		if spec.ExpireAfterSeconds == nil {
+			continue
+		}
+		if _, err := db.Collection(collection).Indexes().DropOne(ctx, spec.Name); err != nil && !isIndexNotFound(err) {
+			return fmt.Errorf("删除%sTTL索引 %s 失败: %v", collection, spec.Name, err)
+		}
+	}
+	return nil
+}
+
+// contentHashIndexError 转换创建content_hash唯一索引时的错误
+// 已存在重复数据时提示运维先清理重复内容，迁移会在下次启动时重试
+func contentHashIndexError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("crawler_contents存在重复的content_hash，无法创建唯一索引；"+
+			"请先调用 POST /api/deduplication/reprocess（dry_run=false）清理重复内容后重启服务: %v", err)
+	}
+	return fmt.Errorf("创建crawler_contents索引失败: %v", err)
+}
+
+// registry 迁移列表，按执行顺序排列；已发布的迁移不要修改或重排，只在末尾追加
+var registry = []Migration{
+	{
+		ID:          "0001_init_db_indexes",
+		Description: "创建creators的 (platform, username) 唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// posts 的 (creator_id, platform, post_id) 唯一索引与30天TTL索引不在此创建：
+			// 定时爬取写入的帖子没有 post_id，TTL 会删除仍在使用的帖子、视频和发布任务
+			return createIndexes(ctx, db, "creators", []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "platform", Value: 1}, {Key: "username", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+			})
+		},
+	},
+	{
+		ID:          "0002_crawler_contents_indexes",
+		Description: "为爬取内容的去重、列表和相似检索创建索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "crawler_contents", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "content_hash", Value: 1}}},
+				{Keys: bson.D{{Key: "url", Value: 1}, {Key: "platform", Value: 1}}},
+				{Keys: bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				{Keys: bson.D{{Key: "created_at", Value: -1}}},
+				{Keys: bson.D{{Key: "simhash_bands", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0003_crawler_tasks_indexes",
+		Description: "为爬取任务的重复检查和列表创建索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "crawler_tasks", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "platform", Value: 1}, {Key: "creator_url", Value: 1}, {Key: "status", Value: 1}}},
+				{Keys: bson.D{{Key: "created_at", Value: -1}}},
+			})
+		},
+	},
+	{
+		ID:          "0004_publish_tasks_platforms_index",
+		Description: "移除publish_tasks上针对不存在字段platform的唯一索引，改为platforms数组索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// 发布任务的平台存储在platforms数组中，旧索引会让同一视频只能创建一个发布任务
+			_, err := db.Collection("publish_tasks").Indexes().DropOne(ctx, "video_id_1_platform_1")
+			if err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("删除publish_tasks旧索引失败: %v", err)
+			}
+
+			return createIndexes(ctx, db, "publish_tasks", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "platforms", Value: 1}, {Key: "status", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0005_http_metrics_hour_index",
+		Description: "为按小时聚合的请求指标创建唯一索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "http_metrics", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "hour", Value: 1}}, Options: options.Index().SetUnique(true)},
+			})
+		},
+	},
+	{
+		ID:          "0006_crawler_content_full_indexes",
+		Description: "为截断内容的完整文本集合创建任务索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "crawler_content_full", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "task_id", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0007_crawler_tasks_deadline_index",
+		Description: "为超时任务检查创建status+deadline索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "crawler_tasks", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "status", Value: 1}, {Key: "deadline", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0008_posts_content_hash_index",
+		Description: "为posts的content_hash创建索引，用于定时爬取的增量去重",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "posts", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "content_hash", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0009_crawler_contents_title_author_index",
+		Description: "为标题+作者去重创建索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "crawler_contents", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "platform", Value: 1}, {Key: "author", Value: 1}, {Key: "title", Value: 1}, {Key: "created_at", Value: -1}}},
+			})
+		},
+	},
+	{
+		ID:          "0010_crawler_contents_content_hash_unique",
+		Description: "将crawler_contents的content_hash索引改为唯一部分索引，作为批量写入的最终去重保证",
+		// 唯一索引不受 DEDUP_ENABLED 与 DEDUP_WINDOW_DAYS/RECORDS 影响：即使关闭去重或限制了比对范围，
+		// content_hash 相同的内容也始终只保留一份
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// 先创建新索引，成功后再删除旧索引，创建失败时原有索引保持不变
+			_, err := db.Collection("crawler_contents").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "content_hash", Value: 1}},
+				Options: options.Index().
+					SetName("content_hash_unique").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"content_hash": bson.M{"$gt": ""}}),
+			})
+			if err != nil {
+				return contentHashIndexError(err)
+			}
+
+			_, err = db.Collection("crawler_contents").Indexes().DropOne(ctx, "content_hash_1")
+			if err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("删除crawler_contents旧索引失败: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "0011_files_hash_index",
+		Description: "为上传文件的哈希映射集合files创建 (hash, folder) 唯一索引及对象名索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "files", []mongo.IndexModel{
+				{Keys: bson.D{{Key: "hash", Value: 1}, {Key: "folder", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "object_key", Value: 1}}},
+			})
+		},
+	},
+	{
+		ID:          "0012_crawler_tasks_unset_headers",
+		Description: "清除crawler_tasks中已保存的自定义请求头（可能包含Cookie等凭据），改为只记录请求头名称",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("crawler_tasks").UpdateMany(ctx,
+				bson.M{"headers": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"headers": ""}},
+			)
+			if err != nil {
+				return fmt.Errorf("清除crawler_tasks请求头失败: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "0013_drop_unsatisfiable_init_indexes",
+		Description: "删除旧版0001创建的posts (creator_id, platform, post_id) 唯一索引及posts、videos、publish_tasks的TTL索引",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").Indexes().DropOne(ctx, "creator_id_1_platform_1_post_id_1")
+			if err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("删除posts唯一索引失败: %v", err)
+			}
+
+			for _, collection := range []string{"posts", "videos", "publish_tasks"} {
+				if err := dropTTLIndexes(ctx, db, collection); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "0014_posts_source_content_id_index",
+		Description: "为物化帖子的来源内容ID创建唯一部分索引，用于物化去重与删除内容时的级联删除",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "posts", []mongo.IndexModel{
+				{
+					Keys: bson.D{{Key: "source_content_id", Value: 1}},
+					Options: options.Index().
+						SetUnique(true).
+						SetPartialFilterExpression(bson.M{"source_content_id": bson.M{"$exists": true}}),
+				},
+			})
+		},
+	},
+}
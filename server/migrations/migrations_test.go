@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestContentHashIndexError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantHint   bool
+		wantPrefix string
+	}{
+		{"重复键", mongo.CommandError{Code: 11000, Message: "E11000 duplicate key error"}, true, "crawler_contents存在重复的content_hash"},
+		{"索引选项冲突", mongo.CommandError{Code: 85, Name: "IndexOptionsConflict"}, false, "创建crawler_contents索引失败"},
+		{"其他错误", errors.New("connection reset"), false, "创建crawler_contents索引失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contentHashIndexError(tt.err).Error()
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("错误信息 = %q，期望以 %q 开头", got, tt.wantPrefix)
+			}
+			if hint := strings.Contains(got, "/api/deduplication/reprocess"); hint != tt.wantHint {
+				t.Errorf("是否提示重新去重 = %v，期望 %v", hint, tt.wantHint)
+			}
+		})
+	}
+}
+
+// TestContentHashUniqueMigrationWithDuplicates 存在重复content_hash时迁移应失败并保留旧索引
+// 需要设置 MONGODB_TEST_URI 指向可写的测试实例，未设置时跳过
+func TestContentHashUniqueMigrationWithDuplicates(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("未设置 MONGODB_TEST_URI，跳过迁移集成测试")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("连接MongoDB失败: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("newshub_migrations_test_" + primitive.NewObjectID().Hex())
+	defer db.Drop(context.Background())
+
+	coll := db.Collection("crawler_contents")
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "content_hash", Value: 1}}}); err != nil {
+		t.Fatalf("创建旧索引失败: %v", err)
+	}
+	if _, err := coll.InsertMany(ctx, []interface{}{
+		bson.M{"content_hash": "dup"},
+		bson.M{"content_hash": "dup"},
+	}); err != nil {
+		t.Fatalf("写入测试数据失败: %v", err)
+	}
+
+	m := findMigration(t, "0010_crawler_contents_content_hash_unique")
+	err = m.Up(ctx, db)
+	if err == nil || !strings.Contains(err.Error(), "/api/deduplication/reprocess") {
+		t.Fatalf("存在重复数据时迁移应提示清理重复内容，实际错误: %v", err)
+	}
+
+	names := indexNames(t, ctx, coll)
+	if !names["content_hash_1"] {
+		t.Error("迁移失败后旧索引 content_hash_1 不应被删除")
+	}
+	if names["content_hash_unique"] {
+		t.Error("迁移失败后不应存在 content_hash_unique 索引")
+	}
+
+	// 清理重复数据后重新执行应成功并替换旧索引
+	if _, err := coll.DeleteOne(ctx, bson.M{"content_hash": "dup"}); err != nil {
+		t.Fatalf("清理重复数据失败: %v", err)
+	}
+	if err := m.Up(ctx, db); err != nil {
+		t.Fatalf("清理后迁移失败: %v", err)
+	}
+	names = indexNames(t, ctx, coll)
+	if names["content_hash_1"] || !names["content_hash_unique"] {
+		t.Errorf("迁移完成后的索引不正确: %v", names)
+	}
+}
+
+// TestDropUnsatisfiableInitIndexes 旧版0001创建的posts唯一索引与TTL索引应被删除，其他索引保留
+// 需要设置 MONGODB_TEST_URI 指向可写的测试实例，未设置时跳过
+func TestDropUnsatisfiableInitIndexes(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("未设置 MONGODB_TEST_URI，跳过迁移集成测试")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("连接MongoDB失败: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("newshub_migrations_test_" + primitive.NewObjectID().Hex())
+	defer db.Drop(context.Background())
+
+	posts := db.Collection("posts")
+	if _, err := posts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "creator_id", Value: 1}, {Key: "platform", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(2592000)},
+		{Keys: bson.D{{Key: "content_hash", Value: 1}}},
+	}); err != nil {
+		t.Fatalf("创建旧索引失败: %v", err)
+	}
+
+	m := findMigration(t, "0013_drop_unsatisfiable_init_indexes")
+	// 重复执行应保持幂等
+	for i := 0; i < 2; i++ {
+		if err := m.Up(ctx, db); err != nil {
+			t.Fatalf("第%d次执行迁移失败: %v", i+1, err)
+		}
+	}
+
+	names := indexNames(t, ctx, posts)
+	if names["creator_id_1_platform_1_post_id_1"] || names["created_at_1"] {
+		t.Errorf("迁移后仍存在应删除的索引: %v", names)
+	}
+	if !names["content_hash_1"] {
+		t.Errorf("迁移不应删除其他索引: %v", names)
+	}
+}
+
+func findMigration(t *testing.T, id string) Migration {
+	t.Helper()
+	for _, m := range registry {
+		if m.ID == id {
+			return m
+		}
+	}
+	t.Fatalf("未找到迁移 %s", id)
+	return Migration{}
+}
+
+func indexNames(t *testing.T, ctx context.Context, coll *mongo.Collection) map[string]bool {
+	t.Helper()
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		t.Fatalf("读取索引失败: %v", err)
+	}
+	names := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		names[spec.Name] = true
+	}
+	return names
+}
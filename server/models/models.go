@@ -11,50 +11,68 @@ type Creator struct {
 	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Username         string             `bson:"username" json:"username" validate:"required"`
 	Platform         string             `bson:"platform" json:"platform" validate:"required"`
-	ProfileURL       string             `bson:"profile_url" json:"profile_url"`                           // 创作者主页URL，用于爬取
-	DisplayName      string             `bson:"display_name" json:"display_name"`                         // 显示名称
-	Avatar           string             `bson:"avatar,omitempty" json:"avatar,omitempty"`                 // 头像URL
-	Description      string             `bson:"description,omitempty" json:"description,omitempty"`       // 描述
-	FollowerCount    int                `bson:"follower_count,omitempty" json:"follower_count,omitempty"` // 粉丝数
-	AutoCrawlEnabled bool               `bson:"auto_crawl_enabled" json:"auto_crawl_enabled"`             // 是否启用自动爬取
-	CrawlInterval    int                `bson:"crawl_interval" json:"crawl_interval"`                     // 爬取间隔（分钟）
-	LastCrawlAt      *time.Time         `bson:"last_crawl_at,omitempty" json:"last_crawl_at,omitempty"`   // 上次爬取时间
-	NextCrawlAt      *time.Time         `bson:"next_crawl_at,omitempty" json:"next_crawl_at,omitempty"`   // 下次爬取时间
-	CrawlStatus      string             `bson:"crawl_status" json:"crawl_status"`                         // idle, crawling, failed
-	CrawlError       string             `bson:"crawl_error,omitempty" json:"crawl_error,omitempty"`       // 爬取错误信息
+	ProfileURL       string             `bson:"profile_url" json:"profile_url"`                                       // 创作者主页URL，用于爬取
+	DisplayName      string             `bson:"display_name" json:"display_name"`                                     // 显示名称
+	Avatar           string             `bson:"avatar,omitempty" json:"avatar,omitempty"`                             // 头像URL
+	Description      string             `bson:"description,omitempty" json:"description,omitempty"`                   // 描述
+	FollowerCount    int                `bson:"follower_count,omitempty" json:"follower_count,omitempty"`             // 粉丝数
+	AutoCrawlEnabled bool               `bson:"auto_crawl_enabled" json:"auto_crawl_enabled"`                         // 是否启用自动爬取
+	CrawlInterval    int                `bson:"crawl_interval" json:"crawl_interval"`                                 // 爬取间隔（分钟）
+	CrawlMode        string             `bson:"crawl_mode,omitempty" json:"crawl_mode,omitempty"`                     // incremental（默认）或 full
+	LastCrawlAt      *time.Time         `bson:"last_crawl_at,omitempty" json:"last_crawl_at,omitempty"`               // 上次爬取时间
+	NextCrawlAt      *time.Time         `bson:"next_crawl_at,omitempty" json:"next_crawl_at,omitempty"`               // 下次爬取时间
+	CrawlStatus      string             `bson:"crawl_status" json:"crawl_status"`                                     // idle, crawling, failed
+	CrawlError       string             `bson:"crawl_error,omitempty" json:"crawl_error,omitempty"`                   // 爬取错误信息
+	CrawlErrorAt     *time.Time         `bson:"crawl_error_at,omitempty" json:"crawl_error_at,omitempty"`             // 最近一次爬取失败时间
+	CrawlFailures    int                `bson:"crawl_failure_streak,omitempty" json:"crawl_failure_streak,omitempty"` // 连续失败次数
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// 创作者爬取模式
+const (
+	CrawlModeIncremental = "incremental" // 只拉取最新内容，Python服务遇到已爬取的内容即可停止翻页
+	CrawlModeFull        = "full"        // 回填历史内容，使用更高的条数上限并翻页到底
+)
+
+// IsValidCrawlMode 是否为支持的爬取模式，空字符串视为默认的增量模式
+func IsValidCrawlMode(mode string) bool {
+	return mode == "" || mode == CrawlModeIncremental || mode == CrawlModeFull
+}
+
 // Post 帖子模型
 type Post struct {
-	ID          primitive.ObjectID `bson:"_id" json:"id"`
-	CreatorID   primitive.ObjectID `bson:"creator_id" json:"creator_id"`
-	CreatorName string             `bson:"creator_name,omitempty" json:"creatorName,omitempty"`
-	Platform    string             `bson:"platform" json:"platform"`
-	PostID      string             `bson:"post_id" json:"post_id"` // 平台原始ID
-	Title       string             `bson:"title,omitempty" json:"title,omitempty"`
-	Content     string             `bson:"content" json:"content"`
-	MediaURLs   []string           `bson:"media_urls" json:"media_urls"`
-	ImageUrl    string             `bson:"image_url,omitempty" json:"imageUrl,omitempty"`
-	VideoUrl    string             `bson:"video_url,omitempty" json:"videoUrl,omitempty"`
-	Likes       int                `bson:"likes,omitempty" json:"likes,omitempty"`
-	Shares      int                `bson:"shares,omitempty" json:"shares,omitempty"`
-	Comments    int                `bson:"comments,omitempty" json:"comments,omitempty"`
-	PublishedAt *time.Time         `bson:"published_at,omitempty" json:"publishedAt,omitempty"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ID              primitive.ObjectID `bson:"_id" json:"id"`
+	CreatorID       primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	CreatorName     string             `bson:"creator_name,omitempty" json:"creatorName,omitempty"`
+	Platform        string             `bson:"platform" json:"platform"`
+	PostID          string             `bson:"post_id" json:"post_id"` // 平台原始ID
+	Title           string             `bson:"title,omitempty" json:"title,omitempty"`
+	Content         string             `bson:"content" json:"content"`
+	ContentHash     string             `bson:"content_hash,omitempty" json:"content_hash,omitempty"`           // 内容哈希，用于增量去重
+	SourceContentID primitive.ObjectID `bson:"source_content_id,omitempty" json:"source_content_id,omitempty"` // 物化来源的crawler_contents ID
+	Tags            []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	MediaURLs       []string           `bson:"media_urls" json:"media_urls"`
+	ImageUrl        string             `bson:"image_url,omitempty" json:"imageUrl,omitempty"`
+	VideoUrl        string             `bson:"video_url,omitempty" json:"videoUrl,omitempty"`
+	Likes           int                `bson:"likes,omitempty" json:"likes,omitempty"`
+	Shares          int                `bson:"shares,omitempty" json:"shares,omitempty"`
+	Comments        int                `bson:"comments,omitempty" json:"comments,omitempty"`
+	PublishedAt     *time.Time         `bson:"published_at,omitempty" json:"publishedAt,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 }
 
 // Video 视频模型
 type Video struct {
-	ID        primitive.ObjectID   `bson:"_id" json:"id"`
-	PostIDs   []primitive.ObjectID `bson:"post_ids" json:"post_ids"`
-	Style     string               `bson:"style" json:"style"`
-	Duration  int                  `bson:"duration" json:"duration"`
-	URL       string               `bson:"url" json:"url"`
-	Status    string               `bson:"status" json:"status"` // processing, completed, failed
-	Error     string               `bson:"error,omitempty" json:"error,omitempty"`
-	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	PostIDs    []primitive.ObjectID `bson:"post_ids" json:"post_ids"`
+	Style      string               `bson:"style" json:"style"`
+	Duration   int                  `bson:"duration" json:"duration"`
+	URL        string               `bson:"url" json:"url"`
+	ObjectName string               `bson:"object_name,omitempty" json:"object_name,omitempty"` // MinIO对象名，为空时使用 videos/<id>.mp4
+	Status     string               `bson:"status" json:"status"`                               // processing, completed, failed
+	Error      string               `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time            `bson:"created_at" json:"created_at"`
 }
 
 // PublishTask 发布任务模型
@@ -66,37 +84,81 @@ type PublishTask struct {
 	Status      string             `bson:"status" json:"status"` // pending, processing, published, failed
 	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
 	PublishedAt string             `bson:"published_at,omitempty" json:"published_at,omitempty"` // 发布后的URL
+	Results     []PublishOutcome   `bson:"results,omitempty" json:"results,omitempty"`           // 各平台的发布结果
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 }
 
+// PublishOutcome 单个平台的发布结果
+type PublishOutcome struct {
+	Platform       string `bson:"platform" json:"platform"`
+	URL            string `bson:"url,omitempty" json:"url,omitempty"`
+	Simulated      bool   `bson:"simulated" json:"simulated"` // 未配置平台凭证时为模拟发布
+	Attempts       int    `bson:"attempts" json:"attempts"`   // 调用平台API的次数，模拟发布为0
+	IdempotencyKey string `bson:"idempotency_key" json:"idempotency_key"`
+	Error          string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
 // CrawlerTask 爬取任务模型
 type CrawlerTask struct {
-	ID          primitive.ObjectID `bson:"_id" json:"id"`
-	Platform    string             `bson:"platform" json:"platform"`
-	CreatorURL  string             `bson:"creator_url" json:"creator_url"`
-	Limit       int                `bson:"limit" json:"limit"`
-	Status      string             `bson:"status" json:"status"` // pending, running, completed, failed
-	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
-	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
-	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Platform       string             `bson:"platform" json:"platform"`
+	CreatorURL     string             `bson:"creator_url" json:"creator_url"`
+	Limit          int                `bson:"limit" json:"limit"`
+	Status         string             `bson:"status" json:"status"`                                       // pending, running, completed, failed
+	Outcome        string             `bson:"outcome,omitempty" json:"outcome,omitempty"`                 // completed, completed_empty
+	FetchedCount   int                `bson:"fetched_count" json:"fetched_count"`                         // Python服务返回的条数
+	SavedCount     int                `bson:"saved_count" json:"saved_count"`                             // 去重后实际保存的条数
+	Progress       int                `bson:"progress,omitempty" json:"progress,omitempty"`               // 运行中任务的进度（0-100），由Python服务上报
+	ItemsCollected int                `bson:"items_collected,omitempty" json:"items_collected,omitempty"` // 运行中已采集的条数
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt      *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt    *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	Deadline       *time.Time         `bson:"deadline,omitempty" json:"deadline,omitempty"`         // 超过该时间仍未完成的任务由调度器标记为失败
+	HeaderNames    []string           `bson:"header_names,omitempty" json:"header_names,omitempty"` // 本次爬取附加的请求头名称，值（如Cookie）只随请求转发，不落库
+	Result         *CrawlerTaskResult `bson:"result,omitempty" json:"-"`                            // Python服务的原始响应，通过 /crawler/tasks/:id/raw 查看
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CrawlerTaskResult Python服务返回的原始响应，超过上限时截断
+type CrawlerTaskResult struct {
+	StatusCode int       `bson:"status_code" json:"status_code"`
+	Body       string    `bson:"body" json:"body"`
+	Size       int       `bson:"size" json:"size"` // 原始响应的字节数
+	Truncated  bool      `bson:"truncated" json:"truncated"`
+	ReceivedAt time.Time `bson:"received_at" json:"received_at"`
 }
 
 // CrawlerContent 爬取内容模型
 type CrawlerContent struct {
-	ID          primitive.ObjectID `bson:"_id" json:"id"`
-	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
-	Title       string             `bson:"title" json:"title"`
-	Content     string             `bson:"content" json:"content"`
-	ContentHash string             `bson:"content_hash" json:"content_hash"` // 内容哈希，用于去重
-	Author      string             `bson:"author" json:"author"`
-	Platform    string             `bson:"platform" json:"platform"`
-	URL         string             `bson:"url" json:"url"`
-	OriginID    string             `bson:"origin_id,omitempty" json:"origin_id,omitempty"` // 平台原始ID
-	PublishedAt *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
-	Tags        []string           `bson:"tags" json:"tags"`
-	Images      []string           `bson:"images" json:"images"`
-	VideoURL    string             `bson:"video_url,omitempty" json:"video_url,omitempty"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	TaskID       primitive.ObjectID `bson:"task_id" json:"task_id"`
+	Title        string             `bson:"title" json:"title"`
+	Content      string             `bson:"content" json:"content"`
+	ContentHash  string             `bson:"content_hash" json:"content_hash"`           // 内容哈希，用于去重
+	SimHash      string             `bson:"simhash,omitempty" json:"simhash,omitempty"` // SimHash指纹（十六进制），用于相似内容检索
+	SimHashBands []string           `bson:"simhash_bands,omitempty" json:"-"`           // SimHash分段索引键
+	Author       string             `bson:"author" json:"author"`
+	Platform     string             `bson:"platform" json:"platform"`
+	URL          string             `bson:"url" json:"url"`
+	OriginID     string             `bson:"origin_id,omitempty" json:"origin_id,omitempty"` // 平台原始ID
+	PublishedAt  *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	Tags         []string           `bson:"tags" json:"tags"`
+	Images       []string           `bson:"images" json:"images"`
+	VideoURL     string             `bson:"video_url,omitempty" json:"video_url,omitempty"`
+	PosterURL    string             `bson:"poster_url,omitempty" json:"poster_url,omitempty"` // 视频封面图URL
+	Likes        int                `bson:"likes,omitempty" json:"likes,omitempty"`           // 点赞数，Python服务返回时才有
+	Shares       int                `bson:"shares,omitempty" json:"shares,omitempty"`         // 转发数
+	Comments     int                `bson:"comments,omitempty" json:"comments,omitempty"`     // 评论数
+	Truncated    bool               `bson:"truncated,omitempty" json:"truncated,omitempty"`   // 标题或正文是否被截断
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CrawlerContentFull 被截断内容的完整文本，_id 与 crawler_contents 中的记录一致
+type CrawlerContentFull struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	TaskID    primitive.ObjectID `bson:"task_id" json:"task_id"`
+	Title     string             `bson:"title" json:"title"`
+	Content   string             `bson:"content" json:"content"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 }
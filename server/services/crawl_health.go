@@ -0,0 +1,178 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"newshub/config"
+)
+
+// 单次爬取的结果类型
+const (
+	CrawlOutcomeReal     = "real"     // 获取到真实内容
+	CrawlOutcomeFallback = "fallback" // 解析失败，使用了备用（合成）内容
+	CrawlOutcomeEmpty    = "empty"    // 请求成功但没有内容
+	CrawlOutcomeError    = "error"    // 请求失败
+)
+
+// maxCrawlHealthEvents 每个平台保留的最大事件数，防止窗口内爬取过多时占用过多内存
+const maxCrawlHealthEvents = 10000
+
+type crawlHealthEvent struct {
+	at      time.Time
+	outcome string
+}
+
+// PlatformCrawlHealth 平台在统计窗口内的爬取健康度
+type PlatformCrawlHealth struct {
+	Platform     string  `json:"platform"`
+	Total        int     `json:"total"`
+	Real         int     `json:"real"`
+	Fallback     int     `json:"fallback"`
+	Empty        int     `json:"empty"`
+	Errors       int     `json:"errors"`
+	SuccessRatio float64 `json:"success_ratio"` // 未出错的比例
+	RealRatio    float64 `json:"real_ratio"`    // 未出错的爬取中获取到真实内容的比例
+	Degraded     bool    `json:"degraded"`
+}
+
+// CrawlHealthTracker 按平台记录最近一段时间的爬取结果，用于发现解析失效或被封禁的平台
+type CrawlHealthTracker struct {
+	window     time.Duration
+	minRatio   float64
+	minSamples int
+
+	events map[string][]crawlHealthEvent
+	mutex  sync.Mutex
+}
+
+var (
+	crawlHealthTracker     *CrawlHealthTracker
+	crawlHealthTrackerOnce sync.Once
+)
+
+// GetCrawlHealthTracker 获取全局爬取健康度统计
+// CRAWL_HEALTH_WINDOW 统计窗口（默认1h），CRAWL_HEALTH_MIN_REAL_RATIO 真实内容比例低于该值时标记为降级（默认0.5），
+// CRAWL_HEALTH_MIN_SAMPLES 样本数达到该值才判断降级（默认5）
+func GetCrawlHealthTracker() *CrawlHealthTracker {
+	crawlHealthTrackerOnce.Do(func() {
+		crawlHealthTracker = NewCrawlHealthTracker(
+			config.GetEnvDuration("CRAWL_HEALTH_WINDOW", time.Hour),
+			config.GetEnvFloat("CRAWL_HEALTH_MIN_REAL_RATIO", 0.5),
+			config.GetEnvInt("CRAWL_HEALTH_MIN_SAMPLES", 5),
+		)
+	})
+	return crawlHealthTracker
+}
+
+// NewCrawlHealthTracker 创建爬取健康度统计
+func NewCrawlHealthTracker(window time.Duration, minRatio float64, minSamples int) *CrawlHealthTracker {
+	if window <= 0 {
+		window = time.Hour
+	}
+	return &CrawlHealthTracker{
+		window:     window,
+		minRatio:   minRatio,
+		minSamples: minSamples,
+		events:     make(map[string][]crawlHealthEvent),
+	}
+}
+
+// RecordCrawlOutcome 记录一次爬取结果到全局统计
+func RecordCrawlOutcome(platform, outcome string) {
+	GetCrawlHealthTracker().Record(platform, outcome)
+}
+
+// ClassifyCrawlOutcome 根据一次爬取的结果判断结果类型
+// total 为返回的内容条数，fallback 为其中由爬虫服务生成的备用内容条数，全部为备用内容时记为 fallback
+func ClassifyCrawlOutcome(succeeded bool, total, fallback int) string {
+	switch {
+	case !succeeded:
+		return CrawlOutcomeError
+	case total == 0:
+		return CrawlOutcomeEmpty
+	case fallback >= total:
+		return CrawlOutcomeFallback
+	default:
+		return CrawlOutcomeReal
+	}
+}
+
+// Record 记录一次爬取结果
+func (t *CrawlHealthTracker) Record(platform, outcome string) {
+	now := time.Now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	events := t.prune(platform, now)
+	if len(events) >= maxCrawlHealthEvents {
+		events = events[1:]
+	}
+	t.events[platform] = append(events, crawlHealthEvent{at: now, outcome: outcome})
+}
+
+// Window 统计窗口长度
+func (t *CrawlHealthTracker) Window() time.Duration {
+	return t.window
+}
+
+// MinRealRatio 判定降级的真实内容比例阈值
+func (t *CrawlHealthTracker) MinRealRatio() float64 {
+	return t.minRatio
+}
+
+// Snapshot 返回各平台在统计窗口内的健康度（按平台名排序）
+func (t *CrawlHealthTracker) Snapshot() []PlatformCrawlHealth {
+	now := time.Now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := make([]PlatformCrawlHealth, 0, len(t.events))
+	for platform := range t.events {
+		events := t.prune(platform, now)
+		if len(events) == 0 {
+			delete(t.events, platform)
+			continue
+		}
+
+		health := PlatformCrawlHealth{Platform: platform, Total: len(events)}
+		for _, event := range events {
+			switch event.outcome {
+			case CrawlOutcomeReal:
+				health.Real++
+			case CrawlOutcomeFallback:
+				health.Fallback++
+			case CrawlOutcomeEmpty:
+				health.Empty++
+			default:
+				health.Errors++
+			}
+		}
+
+		succeeded := health.Total - health.Errors
+		health.SuccessRatio = float64(succeeded) / float64(health.Total)
+		if succeeded > 0 {
+			health.RealRatio = float64(health.Real) / float64(succeeded)
+		}
+		health.Degraded = health.Total >= t.minSamples && health.RealRatio < t.minRatio
+		result = append(result, health)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Platform < result[j].Platform })
+	return result
+}
+
+// prune 移除窗口外的事件，调用方需持有锁
+func (t *CrawlHealthTracker) prune(platform string, now time.Time) []crawlHealthEvent {
+	events := t.events[platform]
+	cutoff := now.Add(-t.window)
+	i := sort.Search(len(events), func(i int) bool { return events[i].at.After(cutoff) })
+	if i > 0 {
+		events = append(events[:0], events[i:]...)
+		t.events[platform] = events
+	}
+	return events
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyCrawlOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		succeeded bool
+		total     int
+		fallback  int
+		want      string
+	}{
+		{"请求失败", false, 3, 0, CrawlOutcomeError},
+		{"没有内容", true, 0, 0, CrawlOutcomeEmpty},
+		{"全部为真实内容", true, 3, 0, CrawlOutcomeReal},
+		{"部分为备用内容", true, 3, 1, CrawlOutcomeReal},
+		{"全部为备用内容", true, 3, 3, CrawlOutcomeFallback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyCrawlOutcome(tt.succeeded, tt.total, tt.fallback); got != tt.want {
+				t.Errorf("ClassifyCrawlOutcome(%v, %d, %d) = %q，期望 %q", tt.succeeded, tt.total, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCrawlHealthFallbackOnlyDegraded 只返回备用内容的平台应被标记为降级
+func TestCrawlHealthFallbackOnlyDegraded(t *testing.T) {
+	tracker := NewCrawlHealthTracker(time.Hour, 0.5, 5)
+	for i := 0; i < 5; i++ {
+		tracker.Record("weibo", ClassifyCrawlOutcome(true, 3, 3))
+		tracker.Record("douyin", ClassifyCrawlOutcome(true, 3, 0))
+	}
+
+	health := make(map[string]PlatformCrawlHealth)
+	for _, h := range tracker.Snapshot() {
+		health[h.Platform] = h
+	}
+
+	if h := health["weibo"]; !h.Degraded || h.Fallback != 5 || h.Real != 0 {
+		t.Errorf("weibo 健康度 = %+v，期望5次备用内容且标记为降级", h)
+	}
+	if h := health["douyin"]; h.Degraded {
+		t.Errorf("douyin 健康度 = %+v，返回真实内容时不应标记为降级", h)
+	}
+}
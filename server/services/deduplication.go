@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"newshub/config"
+	"newshub/utils"
+)
+
+// 重复类型
+const (
+	DuplicateTypeContentHash = "content_hash"
+	DuplicateTypeURL         = "url"
+	DuplicateTypeTitleAuthor = "title_author"
+	DuplicateTypeSimHash     = "simhash"
+)
+
+const (
+	// defaultTitleAuthorWindow 标题+作者去重的默认时间窗口
+	defaultTitleAuthorWindow = 24 * time.Hour
+	// simHashScanLimit 近似去重单次最多比对的候选数量
+	simHashScanLimit = 2000
+)
+
+// DuplicateCheck 一次去重检查的输入
+type DuplicateCheck struct {
+	ContentHash string
+	Platform    string
+	Author      string
+	Title       string // 与入库时一致（截断后）的标题
+	URL         string
+	SimHash     uint64    // 标题+正文的SimHash指纹，为0时跳过近似去重
+	Since       time.Time // 非零时只与该时间之后入库的内容比对
+	// 以下用于对已入库内容重新去重
+	BeforeID primitive.ObjectID // 非零时只与ID更小（更早入库）的内容比对，同时排除自身
+	At       time.Time          // 标题+作者、SimHash时间窗口的参照时间，零值为当前时间
+}
+
+// now 时间窗口的参照时间
+func (c DuplicateCheck) now() time.Time {
+	if c.At.IsZero() {
+		return time.Now()
+	}
+	return c.At
+}
+
+// scope 为查询条件附加 Since 与 BeforeID 限制
+func (c DuplicateCheck) scope(filter bson.M) bson.M {
+	if !c.Since.IsZero() {
+		filter["created_at"] = bson.M{"$gte": c.Since}
+	}
+	if !c.BeforeID.IsZero() {
+		filter["_id"] = bson.M{"$lt": c.BeforeID}
+	}
+	return filter
+}
+
+// DuplicateResult 去重检查结果
+type DuplicateResult struct {
+	Duplicate  bool
+	Type       string             // 命中的重复类型
+	ExistingID primitive.ObjectID // 命中的已入库内容ID（仅simhash类型）
+	Distance   int                // 与已入库内容的海明距离（仅simhash类型）
+}
+
+// DeduplicationService 爬取内容去重服务，统计计数在进程生命周期内累计
+type DeduplicationService struct {
+	db      *mongo.Database
+	enabled int32
+
+	totalChecks     uint64
+	duplicatesFound uint64
+	byType          map[string]*uint64
+	mutex           sync.Mutex
+
+	// 标题+作者去重的时间窗口，按平台配置，未配置的平台使用默认窗口
+	titleAuthorWindows map[string]time.Duration
+	defaultWindow      time.Duration
+	windowMutex        sync.RWMutex
+
+	// SimHash近似去重：同平台、时间窗口内海明距离不超过阈值视为重复，阈值小于0时关闭
+	simHashThreshold int32
+	simHashWindow    int64
+}
+
+var (
+	dedupService     *DeduplicationService
+	dedupServiceOnce sync.Once
+)
+
+// GetDeduplicationService 获取全局去重服务
+// 环境变量 DEDUP_ENABLED=false 时默认关闭；DEDUP_TITLE_AUTHOR_WINDOWS 配置各平台的
+// 标题+作者去重窗口，如 "default=24h,news=72h,weibo=6h"；DEDUP_SIMHASH_THRESHOLD、
+// DEDUP_SIMHASH_WINDOW 配置近似去重的海明距离阈值与比对窗口
+// 注意：crawler_contents 上的 content_hash 唯一索引始终生效，关闭去重或限制比对范围后
+// 完全相同的内容仍会在写入时被拒绝
+func GetDeduplicationService() *DeduplicationService {
+	dedupServiceOnce.Do(func() {
+		dedupService = NewDeduplicationService(config.GetDB())
+		dedupService.SetEnabled(config.GetEnvBool("DEDUP_ENABLED", true))
+		for platform, window := range config.GetDedupTitleAuthorWindows() {
+			dedupService.SetTitleAuthorWindow(platform, window)
+		}
+		dedupService.SetSimHashThreshold(config.GetEnvInt("DEDUP_SIMHASH_THRESHOLD", config.DefaultSimHashThreshold))
+		dedupService.SetSimHashWindow(config.GetEnvDuration("DEDUP_SIMHASH_WINDOW", config.DefaultSimHashWindow))
+	})
+	return dedupService
+}
+
+// NewDeduplicationService 创建去重服务
+func NewDeduplicationService(db *mongo.Database) *DeduplicationService {
+	return &DeduplicationService{
+		db:                 db,
+		enabled:            1,
+		byType:             make(map[string]*uint64),
+		titleAuthorWindows: make(map[string]time.Duration),
+		defaultWindow:      defaultTitleAuthorWindow,
+		simHashThreshold:   config.DefaultSimHashThreshold,
+		simHashWindow:      int64(config.DefaultSimHashWindow),
+	}
+}
+
+// SetSimHashThreshold 设置近似去重的海明距离阈值，小于0时关闭近似去重
+func (s *DeduplicationService) SetSimHashThreshold(threshold int) {
+	atomic.StoreInt32(&s.simHashThreshold, int32(threshold))
+}
+
+// SimHashThreshold 获取近似去重的海明距离阈值
+func (s *DeduplicationService) SimHashThreshold() int {
+	return int(atomic.LoadInt32(&s.simHashThreshold))
+}
+
+// SetSimHashWindow 设置近似去重的比对窗口，d <= 0 时忽略
+func (s *DeduplicationService) SetSimHashWindow(d time.Duration) {
+	if d > 0 {
+		atomic.StoreInt64(&s.simHashWindow, int64(d))
+	}
+}
+
+// SimHashWindow 获取近似去重的比对窗口
+func (s *DeduplicationService) SimHashWindow() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.simHashWindow))
+}
+
+// SetTitleAuthorWindow 设置平台的标题+作者去重窗口，platform 为 "default" 时设置默认窗口
+// d <= 0 时移除该平台的配置
+func (s *DeduplicationService) SetTitleAuthorWindow(platform string, d time.Duration) {
+	s.windowMutex.Lock()
+	defer s.windowMutex.Unlock()
+
+	if platform == "default" {
+		if d > 0 {
+			s.defaultWindow = d
+		}
+		return
+	}
+	platform = config.NormalizePlatform(platform)
+	if d <= 0 {
+		delete(s.titleAuthorWindows, platform)
+		return
+	}
+	s.titleAuthorWindows[platform] = d
+}
+
+// TitleAuthorWindow 获取平台的标题+作者去重窗口
+func (s *DeduplicationService) TitleAuthorWindow(platform string) time.Duration {
+	s.windowMutex.RLock()
+	defer s.windowMutex.RUnlock()
+
+	if window, ok := s.titleAuthorWindows[platform]; ok {
+		return window
+	}
+	return s.defaultWindow
+}
+
+// SetEnabled 启用或关闭去重，不会重置统计计数
+func (s *DeduplicationService) SetEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&s.enabled, value)
+}
+
+// IsEnabled 是否启用去重
+func (s *DeduplicationService) IsEnabled() bool {
+	return atomic.LoadInt32(&s.enabled) == 1
+}
+
+// CheckDuplicate 检查内容是否重复并计入统计
+func (s *DeduplicationService) CheckDuplicate(ctx context.Context, check DuplicateCheck) (DuplicateResult, error) {
+	if !s.IsEnabled() {
+		return DuplicateResult{}, nil
+	}
+
+	atomic.AddUint64(&s.totalChecks, 1)
+	result, err := s.IsDuplicate(ctx, check)
+	if err != nil {
+		return DuplicateResult{}, err
+	}
+	if result.Duplicate {
+		atomic.AddUint64(&s.duplicatesFound, 1)
+		atomic.AddUint64(s.typeCounter(result.Type), 1)
+	}
+	return result, nil
+}
+
+// IsDuplicate 检查内容是否重复但不计入统计，用于预览类场景
+func (s *DeduplicationService) IsDuplicate(ctx context.Context, check DuplicateCheck) (DuplicateResult, error) {
+	coll := s.db.Collection("crawler_contents")
+
+	// 优先检查内容哈希
+	filter := check.scope(bson.M{"content_hash": check.ContentHash})
+	count, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return DuplicateResult{}, err
+	}
+	if count > 0 {
+		return DuplicateResult{Duplicate: true, Type: DuplicateTypeContentHash}, nil
+	}
+
+	// 如果有URL，也检查URL是否重复
+	if check.URL != "" {
+		urlFilter := check.scope(bson.M{
+			"url":      check.URL,
+			"platform": check.Platform,
+		})
+		urlCount, err := coll.CountDocuments(ctx, urlFilter)
+		if err != nil {
+			return DuplicateResult{}, err
+		}
+		if urlCount > 0 {
+			return DuplicateResult{Duplicate: true, Type: DuplicateTypeURL}, nil
+		}
+	}
+
+	// 同一作者在时间窗口内发布的同标题内容视为重复（平台转发、重复推送）
+	if check.Title != "" && check.Author != "" {
+		since := check.now().Add(-s.TitleAuthorWindow(check.Platform))
+		if check.Since.After(since) {
+			since = check.Since
+		}
+		titleFilter := bson.M{
+			"platform":   check.Platform,
+			"author":     check.Author,
+			"title":      check.Title,
+			"created_at": bson.M{"$gte": since},
+		}
+		if !check.BeforeID.IsZero() {
+			titleFilter["_id"] = bson.M{"$lt": check.BeforeID}
+		}
+		titleCount, err := coll.CountDocuments(ctx, titleFilter)
+		if err != nil {
+			return DuplicateResult{}, err
+		}
+		if titleCount > 0 {
+			return DuplicateResult{Duplicate: true, Type: DuplicateTypeTitleAuthor}, nil
+		}
+	}
+
+	// 改写、洗稿后的内容哈希不同，用SimHash海明距离识别
+	return s.findSimHashDuplicate(ctx, check)
+}
+
+// findSimHashDuplicate 在同平台、时间窗口内查找SimHash海明距离不超过阈值的内容
+func (s *DeduplicationService) findSimHashDuplicate(ctx context.Context, check DuplicateCheck) (DuplicateResult, error) {
+	threshold := s.SimHashThreshold()
+	if threshold < 0 || check.SimHash == 0 {
+		return DuplicateResult{}, nil
+	}
+
+	since := check.now().Add(-s.SimHashWindow())
+	if check.Since.After(since) {
+		since = check.Since
+	}
+	filter := bson.M{
+		"platform":   check.Platform,
+		"simhash":    bson.M{"$exists": true, "$ne": ""},
+		"created_at": bson.M{"$gte": since},
+	}
+	if !check.BeforeID.IsZero() {
+		filter["_id"] = bson.M{"$lt": check.BeforeID}
+	}
+	// 阈值在分段索引可召回的范围内时，只比对至少有一个分段相同的内容
+	if threshold < utils.SimHashBandCount {
+		filter["simhash_bands"] = bson.M{"$in": utils.SimHashBands(check.SimHash)}
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, "simhash": 1}).
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(simHashScanLimit)
+	cursor, err := s.db.Collection("crawler_contents").Find(ctx, filter, opts)
+	if err != nil {
+		return DuplicateResult{}, err
+	}
+	defer cursor.Close(ctx)
+
+	best := DuplicateResult{Distance: threshold + 1}
+	for cursor.Next(ctx) {
+		var candidate struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			SimHash string             `bson:"simhash"`
+		}
+		if err := cursor.Decode(&candidate); err != nil {
+			continue
+		}
+		fingerprint, err := utils.ParseSimHash(candidate.SimHash)
+		if err != nil {
+			continue
+		}
+		if distance := utils.HammingDistance(check.SimHash, fingerprint); distance < best.Distance {
+			best = DuplicateResult{
+				Duplicate:  true,
+				Type:       DuplicateTypeSimHash,
+				ExistingID: candidate.ID,
+				Distance:   distance,
+			}
+			if distance == 0 {
+				break
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return DuplicateResult{}, err
+	}
+	if !best.Duplicate {
+		return DuplicateResult{}, nil
+	}
+	return best, nil
+}
+
+// WindowStart 根据去重范围配置计算比对的起始时间，不限制时返回零值
+// 同时配置天数与条数时取范围较小者
+func (s *DeduplicationService) WindowStart(ctx context.Context) (time.Time, error) {
+	window := config.GetDedupWindow()
+
+	var since time.Time
+	if window.Days > 0 {
+		since = time.Now().AddDate(0, 0, -window.Days)
+	}
+
+	if window.Records > 0 {
+		opts := options.FindOne().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetSkip(int64(window.Records - 1)).
+			SetProjection(bson.M{"created_at": 1})
+
+		var boundary struct {
+			CreatedAt time.Time `bson:"created_at"`
+		}
+		err := s.db.Collection("crawler_contents").FindOne(ctx, bson.M{}, opts).Decode(&boundary)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return time.Time{}, err
+		}
+		// 记录数不足N条时不限制
+		if err == nil && boundary.CreatedAt.After(since) {
+			since = boundary.CreatedAt
+		}
+	}
+
+	return since, nil
+}
+
+// Counters 返回累计的检查次数与发现的重复数
+func (s *DeduplicationService) Counters() (totalChecks, duplicatesFound uint64) {
+	return atomic.LoadUint64(&s.totalChecks), atomic.LoadUint64(&s.duplicatesFound)
+}
+
+// GetStats 获取去重统计：内容总数、今日新增及累计的检查计数
+func (s *DeduplicationService) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	coll := s.db.Collection("crawler_contents")
+
+	totalContents, err := coll.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayContents, err := coll.CountDocuments(ctx, bson.M{"created_at": bson.M{"$gte": today}})
+	if err != nil {
+		return nil, err
+	}
+
+	totalChecks, duplicatesFound := s.Counters()
+
+	s.mutex.Lock()
+	byType := make(map[string]uint64, len(s.byType))
+	for duplicateType, counter := range s.byType {
+		byType[duplicateType] = atomic.LoadUint64(counter)
+	}
+	s.mutex.Unlock()
+
+	s.windowMutex.RLock()
+	windows := make(map[string]string, len(s.titleAuthorWindows)+1)
+	windows["default"] = s.defaultWindow.String()
+	for platform, window := range s.titleAuthorWindows {
+		windows[platform] = window.String()
+	}
+	s.windowMutex.RUnlock()
+
+	return map[string]interface{}{
+		"enabled":              s.IsEnabled(),
+		"total_contents":       totalContents,
+		"today_contents":       todayContents,
+		"total_checks":         totalChecks,
+		"duplicates_found":     duplicatesFound,
+		"by_type":              byType,
+		"title_author_windows": windows,
+		"simhash_threshold":    s.SimHashThreshold(),
+		"simhash_window":       s.SimHashWindow().String(),
+	}, nil
+}
+
+// typeCounter 获取指定重复类型的计数器，不存在时创建
+func (s *DeduplicationService) typeCounter(duplicateType string) *uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter, ok := s.byType[duplicateType]
+	if !ok {
+		counter = new(uint64)
+		s.byType[duplicateType] = counter
+	}
+	return counter
+}
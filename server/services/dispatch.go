@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"newshub/config"
+)
+
+// dispatchLimiter 限制同时发往Python爬虫服务的请求数
+type dispatchLimiter struct {
+	slots    chan struct{}
+	inFlight int64
+	waiting  int64
+}
+
+var (
+	pythonDispatch     *dispatchLimiter
+	pythonDispatchOnce sync.Once
+)
+
+// getPythonDispatch 按 CRAWLER_MAX_INFLIGHT（默认5）初始化全局限制器
+func getPythonDispatch() *dispatchLimiter {
+	pythonDispatchOnce.Do(func() {
+		limit := config.GetEnvInt("CRAWLER_MAX_INFLIGHT", 5)
+		if limit <= 0 {
+			limit = 5
+		}
+		pythonDispatch = &dispatchLimiter{slots: make(chan struct{}, limit)}
+	})
+	return pythonDispatch
+}
+
+// AcquirePythonDispatch 获取一个Python爬虫调用名额，名额用尽时等待直到释放或ctx结束
+// 返回的release必须在调用完成后执行
+func AcquirePythonDispatch(ctx context.Context) (func(), error) {
+	limiter := getPythonDispatch()
+
+	atomic.AddInt64(&limiter.waiting, 1)
+	defer atomic.AddInt64(&limiter.waiting, -1)
+
+	select {
+	case limiter.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	atomic.AddInt64(&limiter.inFlight, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&limiter.inFlight, -1)
+			<-limiter.slots
+		})
+	}, nil
+}
+
+// PythonDispatchStats 返回当前进行中、等待中的调用数及上限
+func PythonDispatchStats() (inFlight, waiting int64, limit int) {
+	limiter := getPythonDispatch()
+	return atomic.LoadInt64(&limiter.inFlight), atomic.LoadInt64(&limiter.waiting), cap(limiter.slots)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrFFmpegUnavailable 系统中未安装ffmpeg
+var ErrFFmpegUnavailable = errors.New("ffmpeg不可用")
+
+// PosterService 视频封面提取服务
+type PosterService struct {
+	storage    *StorageService
+	ffmpegPath string
+}
+
+// NewPosterService 创建封面提取服务，ffmpeg不存在时服务不可用但不报错
+func NewPosterService() *PosterService {
+	ffmpegPath, _ := exec.LookPath("ffmpeg")
+	return &PosterService{
+		storage:    NewStorageService(),
+		ffmpegPath: ffmpegPath,
+	}
+}
+
+// Available 是否可以提取封面
+func (s *PosterService) Available() bool {
+	return s.ffmpegPath != ""
+}
+
+// CreatePoster 从视频URL截取一帧作为封面并上传到MinIO
+func (s *PosterService) CreatePoster(ctx context.Context, videoURL string) (*FileInfo, error) {
+	if !s.Available() {
+		return nil, ErrFFmpegUnavailable
+	}
+
+	parsed, err := url.Parse(videoURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("不支持的视频地址: %s", videoURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// 截取第1秒的画面，以JPEG格式输出到标准输出
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-ss", "1",
+		"-i", videoURL,
+		"-frames:v", "1",
+		"-f", "image2", "-vcodec", "mjpeg",
+		"pipe:1",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("提取视频封面失败: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("提取视频封面失败: 未获取到画面")
+	}
+
+	return s.storage.UploadBytes(ctx, stdout.Bytes(), "posters", ".jpg", "image/jpeg")
+}
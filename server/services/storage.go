@@ -1,16 +1,23 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"io"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"newshub/config"
 )
 
@@ -38,88 +45,121 @@ func NewStorageService() *StorageService {
 	}
 }
 
-// UploadFile 上传文件
-func (s *StorageService) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader, folder string) (*FileInfo, error) {
-	// 生成文件哈希
-	hash, err := s.calculateFileHash(file)
+// UploadFile 上传文件，contentType 为调用方校验后的文件类型
+func (s *StorageService) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader, folder, contentType string) (*FileInfo, error) {
+	// 读取文件并生成哈希，大文件转存到临时文件
+	buffer, err := bufferUpload(file)
 	if err != nil {
 		return nil, fmt.Errorf("计算文件哈希失败: %v", err)
 	}
+	defer buffer.Close()
 
-	// 重置文件指针
-	file.Seek(0, 0)
+	return s.putBuffer(ctx, buffer, folder, filepath.Ext(header.Filename), contentType)
+}
 
+// putBuffer 将已计算哈希的数据上传到MinIO，哈希相同的文件已存在时直接返回
+func (s *StorageService) putBuffer(ctx context.Context, buffer *uploadBuffer, folder, fileExt, contentType string) (*FileInfo, error) {
 	// 生成文件名
-	fileExt := filepath.Ext(header.Filename)
-	fileName := fmt.Sprintf("%s/%s_%d%s", folder, hash, time.Now().Unix(), fileExt)
+	fileName := fmt.Sprintf("%s/%s_%d%s", folder, buffer.Hash, time.Now().Unix(), fileExt)
 
 	// 检查文件是否已存在（去重）
-	existingFile, err := s.GetFileByHash(ctx, hash)
+	existingFile, err := s.GetFileByHash(ctx, folder, buffer.Hash)
 	if err == nil && existingFile != nil {
 		return existingFile, nil // 返回已存在的文件
 	}
 
 	// 上传文件到MinIO
-	info, err := s.client.PutObject(ctx, s.bucketName, fileName, file, header.Size, minio.PutObjectOptions{
-		ContentType: header.Header.Get("Content-Type"),
+	info, err := s.client.PutObject(ctx, s.bucketName, fileName, buffer, buffer.Size, minio.PutObjectOptions{
+		ContentType: contentType,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("上传文件失败: %v", err)
 	}
 
-	// 生成访问URL
-	url := s.generateFileURL(fileName)
+	fileInfo := &FileInfo{
+		FileName:    fileName,
+		FileSize:    info.Size,
+		ContentType: contentType,
+		URL:         s.generateFileURL(fileName),
+		Hash:        buffer.Hash,
+		UploadedAt:  time.Now(),
+	}
+	s.recordFile(ctx, folder, fileInfo)
+	return fileInfo, nil
+}
+
+// UploadBytes 上传内存中的数据，命名规则与UploadFile一致
+func (s *StorageService) UploadBytes(ctx context.Context, data []byte, folder, fileExt, contentType string) (*FileInfo, error) {
+	hash := fmt.Sprintf("%x", md5.Sum(data))
+	fileName := fmt.Sprintf("%s/%s_%d%s", folder, hash, time.Now().Unix(), fileExt)
+
+	info, err := s.client.PutObject(ctx, s.bucketName, fileName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("上传文件失败: %v", err)
+	}
 
 	return &FileInfo{
 		FileName:    fileName,
 		FileSize:    info.Size,
-		ContentType: header.Header.Get("Content-Type"),
-		URL:         url,
+		ContentType: contentType,
+		URL:         s.generateFileURL(fileName),
 		Hash:        hash,
 		UploadedAt:  time.Now(),
 	}, nil
 }
 
-// UploadFromURL 从URL下载并上传文件
-func (s *StorageService) UploadFromURL(ctx context.Context, url, folder string) (*FileInfo, error) {
-	// 这里需要实现从URL下载文件的逻辑
-	// 暂时返回错误，后续实现
-	return nil, fmt.Errorf("从URL上传功能待实现")
-}
-
 // DeleteFile 删除文件
 func (s *StorageService) DeleteFile(ctx context.Context, fileName string) error {
 	err := s.client.RemoveObject(ctx, s.bucketName, fileName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("删除文件失败: %v", err)
 	}
+	if db := config.GetDB(); db != nil {
+		if _, err := db.Collection(filesCollection).DeleteMany(ctx, bson.M{"object_key": fileName}); err != nil {
+			log.Printf("删除文件哈希映射失败: %v", err)
+		}
+	}
 	return nil
 }
 
-// GetFileByHash 根据哈希查找文件
-func (s *StorageService) GetFileByHash(ctx context.Context, hash string) (*FileInfo, error) {
-	// 遍历bucket中的对象，查找匹配的哈希
+// GetFileByHash 在文件夹中查找哈希相同的文件
+// 优先查询files集合中的哈希映射，没有记录时按 folder/<hash>_ 前缀列举对象（兼容映射建立前上传的文件）
+func (s *StorageService) GetFileByHash(ctx context.Context, folder, hash string) (*FileInfo, error) {
+	if db := config.GetDB(); db != nil {
+		var record storedFile
+		err := db.Collection(filesCollection).FindOne(ctx, bson.M{"hash": hash, "folder": folder}).Decode(&record)
+		if err == nil {
+			// 对象可能已在映射之外被删除，确认仍存在后再复用
+			if _, statErr := s.client.StatObject(ctx, s.bucketName, record.ObjectKey, minio.StatObjectOptions{}); statErr == nil {
+				return record.fileInfo(s.generateFileURL(record.ObjectKey)), nil
+			}
+			db.Collection(filesCollection).DeleteOne(ctx, bson.M{"_id": record.ID})
+		} else if err != mongo.ErrNoDocuments {
+			log.Printf("查询文件哈希映射失败: %v", err)
+		}
+	}
+
 	objectCh := s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{
+		Prefix:    folder + "/" + hash + "_",
 		Recursive: true,
 	})
-
 	for object := range objectCh {
 		if object.Err != nil {
 			continue
 		}
 
-		// 从文件名中提取哈希
-		if strings.Contains(object.Key, hash) {
-			url := s.generateFileURL(object.Key)
-			return &FileInfo{
-				FileName:    object.Key,
-				FileSize:    object.Size,
-				ContentType: "", // 需要额外查询
-				URL:         url,
-				Hash:        hash,
-				UploadedAt:  object.LastModified,
-			}, nil
+		fileInfo := &FileInfo{
+			FileName:    object.Key,
+			FileSize:    object.Size,
+			ContentType: object.ContentType,
+			URL:         s.generateFileURL(object.Key),
+			Hash:        hash,
+			UploadedAt:  object.LastModified,
 		}
+		s.recordFile(ctx, folder, fileInfo)
+		return fileInfo, nil
 	}
 
 	return nil, fmt.Errorf("文件未找到")
@@ -159,16 +199,6 @@ func (s *StorageService) ListFiles(ctx context.Context, folder string, limit int
 	return files, nil
 }
 
-// calculateFileHash 计算文件MD5哈希
-func (s *StorageService) calculateFileHash(file multipart.File) (string, error) {
-	hash := md5.New()
-	_, err := io.Copy(hash, file)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-}
-
 // generateFileURL 生成文件访问URL
 func (s *StorageService) generateFileURL(fileName string) string {
 	minioConfig := config.GetMinIOConfig()
@@ -179,6 +209,65 @@ func (s *StorageService) generateFileURL(fileName string) string {
 	return fmt.Sprintf("%s://%s/%s/%s", protocol, minioConfig.Endpoint, minioConfig.BucketName, fileName)
 }
 
+// PresignedUpload 客户端直传MinIO所需的信息
+// 客户端以 multipart/form-data POST 到 URL，依次携带 FormData 中的全部字段，最后是 file 字段
+type PresignedUpload struct {
+	URL         string            `json:"url"`
+	FormData    map[string]string `json:"form_data"`    // 上传策略及签名字段
+	ObjectKey   string            `json:"object_key"`   // 上传完成后文件在bucket中的对象名
+	FileURL     string            `json:"file_url"`     // 上传完成后的访问URL
+	ContentType string            `json:"content_type"` // 表单中必须携带的Content-Type
+	MaxBytes    int64             `json:"max_bytes"`    // 允许上传的最大字节数，0表示不限制
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// GeneratePresignedPost 生成预签名的POST上传策略，客户端可直接上传到MinIO而不经过API服务
+// 策略限定了对象名、Content-Type与文件大小（1~maxBytes字节，maxBytes<=0时不限制），MinIO会拒绝不符合策略的上传
+// 对象名为 folder/<时间戳>_<随机串><扩展名>，扩展名取自原文件名
+// 直传的文件不经过API服务，不会计算哈希，也不会写入files集合参与上传去重
+func (s *StorageService) GeneratePresignedPost(ctx context.Context, folder, filename, contentType string, maxBytes int64, expiry time.Duration) (*PresignedUpload, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("生成对象名失败: %v", err)
+	}
+	objectKey := fmt.Sprintf("%s/%d_%s%s", folder, time.Now().Unix(), hex.EncodeToString(random), strings.ToLower(filepath.Ext(filename)))
+	expiresAt := time.Now().Add(expiry)
+
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(s.bucketName); err != nil {
+		return nil, fmt.Errorf("生成上传策略失败: %v", err)
+	}
+	if err := policy.SetKey(objectKey); err != nil {
+		return nil, fmt.Errorf("生成上传策略失败: %v", err)
+	}
+	if err := policy.SetExpires(expiresAt); err != nil {
+		return nil, fmt.Errorf("生成上传策略失败: %v", err)
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return nil, fmt.Errorf("生成上传策略失败: %v", err)
+	}
+	if maxBytes > 0 {
+		if err := policy.SetContentLengthRange(1, maxBytes); err != nil {
+			return nil, fmt.Errorf("生成上传策略失败: %v", err)
+		}
+	}
+
+	url, formData, err := s.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, fmt.Errorf("生成预签名上传策略失败: %v", err)
+	}
+
+	return &PresignedUpload{
+		URL:         url.String(),
+		FormData:    formData,
+		ObjectKey:   objectKey,
+		FileURL:     s.generateFileURL(objectKey),
+		ContentType: contentType,
+		MaxBytes:    maxBytes,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
 // GetFileURL 获取文件的预签名URL（用于临时访问）
 func (s *StorageService) GetFileURL(ctx context.Context, fileName string, expiry time.Duration) (string, error) {
 	url, err := s.client.PresignedGetObject(ctx, s.bucketName, fileName, expiry, nil)
@@ -186,4 +275,51 @@ func (s *StorageService) GetFileURL(ctx context.Context, fileName string, expiry
 		return "", fmt.Errorf("生成预签名URL失败: %v", err)
 	}
 	return url.String(), nil
-}
\ No newline at end of file
+}
+
+// filesCollection 上传文件的哈希到对象名映射，用于上传去重
+const filesCollection = "files"
+
+// storedFile files集合中的记录，(hash, folder) 唯一
+type storedFile struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Hash        string             `bson:"hash"`
+	Folder      string             `bson:"folder"`
+	ObjectKey   string             `bson:"object_key"`
+	Size        int64              `bson:"size"`
+	ContentType string             `bson:"content_type,omitempty"`
+	UploadedAt  time.Time          `bson:"uploaded_at"`
+}
+
+func (f storedFile) fileInfo(url string) *FileInfo {
+	return &FileInfo{
+		FileName:    f.ObjectKey,
+		FileSize:    f.Size,
+		ContentType: f.ContentType,
+		URL:         url,
+		Hash:        f.Hash,
+		UploadedAt:  f.UploadedAt,
+	}
+}
+
+// recordFile 记录文件的哈希映射，失败时只记录日志（下次查找会回退到前缀列举）
+func (s *StorageService) recordFile(ctx context.Context, folder string, file *FileInfo) {
+	db := config.GetDB()
+	if db == nil {
+		return
+	}
+	_, err := db.Collection(filesCollection).UpdateOne(ctx,
+		bson.M{"hash": file.Hash, "folder": folder},
+		bson.M{"$set": storedFile{
+			Hash:        file.Hash,
+			Folder:      folder,
+			ObjectKey:   file.FileName,
+			Size:        file.FileSize,
+			ContentType: file.ContentType,
+			UploadedAt:  file.UploadedAt,
+		}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		log.Printf("记录文件哈希映射失败: %v", err)
+	}
+}
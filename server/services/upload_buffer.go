@@ -0,0 +1,71 @@
+package services
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+
+	"newshub/config"
+)
+
+// uploadBuffer 已计算哈希、可重复读取的上传数据
+type uploadBuffer struct {
+	io.ReadSeeker
+	Size int64
+	Hash string
+
+	file *os.File
+}
+
+// bufferUpload 读取上传数据并计算MD5
+// 不超过阈值时数据保存在内存中；超过阈值时边读边写入临时文件，内存占用不超过阈值
+// 调用方必须调用 Close 释放临时文件
+func bufferUpload(r io.Reader) (*uploadBuffer, error) {
+	threshold := config.GetUploadStreamThreshold()
+
+	data, err := io.ReadAll(io.LimitReader(r, threshold+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取上传数据失败: %v", err)
+	}
+	if int64(len(data)) <= threshold {
+		return &uploadBuffer{
+			ReadSeeker: bytes.NewReader(data),
+			Size:       int64(len(data)),
+			Hash:       fmt.Sprintf("%x", md5.Sum(data)),
+		}, nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "newshub-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+
+	hash := md5.New()
+	size, err := io.Copy(tmpFile, io.TeeReader(io.MultiReader(bytes.NewReader(data), r), hash))
+	if err == nil {
+		_, err = tmpFile.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("写入临时文件失败: %v", err)
+	}
+
+	return &uploadBuffer{
+		ReadSeeker: tmpFile,
+		Size:       size,
+		Hash:       fmt.Sprintf("%x", hash.Sum(nil)),
+		file:       tmpFile,
+	}, nil
+}
+
+// Close 删除临时文件（内存缓冲时无操作）
+func (b *uploadBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
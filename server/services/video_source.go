@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"newshub/config"
+)
+
+// ErrVideoNotFound 本地磁盘与MinIO中均找不到视频文件
+var ErrVideoNotFound = errors.New("视频文件不存在")
+
+// VideoSource 已打开的视频资源，可能来自本地磁盘或MinIO
+type VideoSource struct {
+	io.ReadCloser
+	Name        string
+	Size        int64 // 未知时为-1
+	ContentType string
+	Backend     string // local 或 minio
+}
+
+// VideoObjectName 返回视频在MinIO中的对象名，未记录时使用默认路径
+func VideoObjectName(videoID, objectName string) string {
+	if objectName != "" {
+		return objectName
+	}
+	return "videos/" + videoID + ".mp4"
+}
+
+// OpenVideo 打开视频文件，优先读取本地磁盘，不存在时通过预签名URL从MinIO读取
+func (s *StorageService) OpenVideo(ctx context.Context, videoID, objectName string) (*VideoSource, error) {
+	localPath := config.GetVideoPath(videoID)
+	if file, err := os.Open(localPath); err == nil {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &VideoSource{
+			ReadCloser:  file,
+			Name:        filepath.Base(localPath),
+			Size:        info.Size(),
+			ContentType: "video/mp4",
+			Backend:     "local",
+		}, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if s.client == nil {
+		return nil, ErrVideoNotFound
+	}
+
+	objectName = VideoObjectName(videoID, objectName)
+	url, err := s.GetFileURL(ctx, objectName, 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("从MinIO读取视频失败: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrVideoNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("从MinIO读取视频失败: HTTP %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	return &VideoSource{
+		ReadCloser:  resp.Body,
+		Name:        filepath.Base(objectName),
+		Size:        resp.ContentLength,
+		ContentType: contentType,
+		Backend:     "minio",
+	}, nil
+}
+
+// ResolveVideoFile 返回可供发布使用的本地视频文件路径
+// 视频存放在MinIO时会下载到临时文件，调用方使用完毕后需调用cleanup
+func (s *StorageService) ResolveVideoFile(ctx context.Context, videoID, objectName string) (string, func(), error) {
+	localPath := config.GetVideoPath(videoID)
+	if _, err := os.Stat(localPath); err == nil {
+		return localPath, func() {}, nil
+	}
+
+	source, err := s.OpenVideo(ctx, videoID, objectName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer source.Close()
+
+	tmpFile, err := os.CreateTemp("", "newshub-video-*"+filepath.Ext(source.Name))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(tmpFile.Name()) }
+
+	if _, err := io.Copy(tmpFile, source); err != nil {
+		tmpFile.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("下载视频失败: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tmpFile.Name(), cleanup, nil
+}
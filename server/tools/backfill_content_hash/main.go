@@ -0,0 +1,150 @@
+// backfill_content_hash 为缺少 content_hash 的爬取内容补算内容哈希
+//
+// 用法（在 server 目录下）：
+//
+//	go run ./tools/backfill_content_hash [-dry-run] [-batch 500] [-all]
+//
+// 哈希规则与入库时一致（utils.TitleContentHash），截断的内容使用 crawler_content_full 中的完整文本，
+// 标准化步骤读取 CONTENT_HASH_NORMALIZATION。修改 CONTENT_HASH_NORMALIZATION 后使用 -all 重新计算全部内容的哈希。补算出的哈希与已有内容冲突时（唯一索引报错）跳过，
+// 这些重复内容可通过 /api/deduplication/reprocess 清理。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"newshub/config"
+	"newshub/utils"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只统计需要补算的数量，不写入数据库")
+	batchSize := flag.Int("batch", 500, "每批写入的条数")
+	all := flag.Bool("all", false, "重新计算全部内容的哈希，而不只是缺少哈希的内容")
+	flag.Parse()
+	if *batchSize <= 0 {
+		*batchSize = 500
+	}
+
+	fmt.Println("=== 补算爬取内容的内容哈希 ===")
+
+	// 加载环境变量
+	if err := godotenv.Load(".env"); err != nil {
+		log.Printf("警告：未找到.env文件，使用默认配置\n")
+	}
+
+	normalization, err := utils.ParseHashNormalization(config.GetContentHashNormalization())
+	if err != nil {
+		log.Printf("警告：%v，使用精确匹配\n", err)
+	}
+	utils.SetHashNormalization(normalization)
+
+	if err := config.ConnectDB(); err != nil {
+		log.Fatalf("连接数据库失败: %v", err)
+	}
+	db := config.GetDB()
+	defer db.Client().Disconnect(context.Background())
+
+	ctx := context.Background()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"content_hash": bson.M{"$exists": false}},
+		bson.M{"content_hash": ""},
+		bson.M{"content_hash": nil},
+	}}
+	if *all {
+		filter = bson.M{}
+	}
+	total, err := db.Collection("crawler_contents").CountDocuments(ctx, filter)
+	if err != nil {
+		log.Fatalf("统计待补算内容失败: %v", err)
+	}
+	fmt.Printf("待计算内容哈希的记录: %d\n", total)
+	if total == 0 || *dryRun {
+		return
+	}
+
+	cursor, err := db.Collection("crawler_contents").Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "title": 1, "content": 1, "truncated": 1}))
+	if err != nil {
+		log.Fatalf("查询爬取内容失败: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		scanned   int
+		updated   int64
+		conflicts int
+		batch     []mongo.WriteModel
+	)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		result, err := db.Collection("crawler_contents").BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			updated += result.ModifiedCount
+		}
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) {
+			for _, writeErr := range bulkErr.WriteErrors {
+				if mongo.IsDuplicateKeyError(writeErr) {
+					conflicts++
+				} else {
+					log.Printf("写入内容哈希失败: %v", writeErr)
+				}
+			}
+		} else if err != nil {
+			log.Fatalf("写入内容哈希失败: %v", err)
+		}
+		batch = batch[:0]
+		fmt.Printf("进度: %d/%d，已更新 %d，哈希冲突 %d\n", scanned, total, updated, conflicts)
+	}
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			Title     string             `bson:"title"`
+			Content   string             `bson:"content"`
+			Truncated bool               `bson:"truncated"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("解析爬取内容失败: %v", err)
+			continue
+		}
+		scanned++
+
+		title, content := doc.Title, doc.Content
+		if doc.Truncated {
+			var full struct {
+				Title   string `bson:"title"`
+				Content string `bson:"content"`
+			}
+			if err := db.Collection("crawler_content_full").FindOne(ctx, bson.M{"_id": doc.ID}).Decode(&full); err == nil {
+				title, content = full.Title, full.Content
+			}
+		}
+
+		batch = append(batch, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"content_hash": utils.TitleContentHash(title, content)}}))
+		if len(batch) >= *batchSize {
+			flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("遍历爬取内容失败: %v", err)
+	}
+	flush()
+
+	fmt.Printf("完成: 扫描 %d 条，更新 %d 条，哈希冲突 %d 条\n", scanned, updated, conflicts)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// HashNormalization 计算内容哈希前的标准化步骤，零值为精确匹配（仅处理换行与首尾空白）
+type HashNormalization struct {
+	StripHTML          bool // 去除HTML标签并反转义实体
+	Lowercase          bool // 转为小写
+	StripPunctuation   bool // 去除标点与符号
+	CollapseWhitespace bool // 合并连续空白
+}
+
+var (
+	hashNormalization atomic.Value // HashNormalization
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseHashNormalization 解析逗号分隔的标准化步骤：html、lowercase、punctuation、whitespace，
+// "exact" 或空字符串表示精确匹配，"all" 表示启用全部步骤
+func ParseHashNormalization(spec string) (HashNormalization, error) {
+	var n HashNormalization
+	for _, step := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case "", "exact":
+		case "html":
+			n.StripHTML = true
+		case "lowercase":
+			n.Lowercase = true
+		case "punctuation":
+			n.StripPunctuation = true
+		case "whitespace":
+			n.CollapseWhitespace = true
+		case "all":
+			n = HashNormalization{StripHTML: true, Lowercase: true, StripPunctuation: true, CollapseWhitespace: true}
+		default:
+			return HashNormalization{}, fmt.Errorf("未知的哈希标准化步骤: %q", step)
+		}
+	}
+	return n, nil
+}
+
+// SetHashNormalization 设置全局的哈希标准化步骤，所有ContentHash调用共用
+// 修改后新旧内容的哈希不再可比，已入库内容需要重新计算哈希
+func SetHashNormalization(n HashNormalization) {
+	hashNormalization.Store(n)
+}
+
+// GetHashNormalization 获取当前的哈希标准化步骤
+func GetHashNormalization() HashNormalization {
+	n, _ := hashNormalization.Load().(HashNormalization)
+	return n
+}
+
+// NormalizeForHash 按标准化步骤处理内容
+func (n HashNormalization) NormalizeForHash(content string) string {
+	if n.StripHTML {
+		content = html.UnescapeString(htmlTagPattern.ReplaceAllString(content, " "))
+	}
+	if n.Lowercase {
+		content = strings.ToLower(content)
+	}
+	if n.StripPunctuation {
+		content = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				return -1
+			}
+			return r
+		}, content)
+	}
+	if n.CollapseWhitespace {
+		return strings.Join(strings.Fields(content), " ")
+	}
+
+	normalized := strings.TrimSpace(strings.ReplaceAll(content, "\n", " "))
+	return strings.ReplaceAll(normalized, "\r", "")
+}
+
+// ContentHash 计算内容的SHA-256哈希，用于去重
+// 标准化：换行替换为空格、去除首尾空白和回车符，再按 SetHashNormalization 配置的步骤处理
+func ContentHash(content string) string {
+	normalized := GetHashNormalization().NormalizeForHash(content)
+
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// titleContentSeparator 去除标点时标题与正文之间的分隔符，不会被任何标准化步骤去除或合并
+const titleContentSeparator = "\x00"
+
+// TitleContentHash 计算标题+正文的内容哈希，入库、去重与补算哈希共用
+// 默认按 title + "|" + content 计算，与已入库的哈希保持一致；
+// 启用去除标点时 "|" 会被去掉，使 "ab"+"c" 与 "a"+"bc" 哈希相同，此时标题与正文分别标准化后用 NUL 拼接
+func TitleContentHash(title, content string) string {
+	n := GetHashNormalization()
+	if !n.StripPunctuation {
+		return ContentHash(title + "|" + content)
+	}
+	normalized := n.NormalizeForHash(title) + titleContentSeparator + n.NormalizeForHash(content)
+
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
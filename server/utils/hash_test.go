@@ -0,0 +1,132 @@
+package utils
+
+import "testing"
+
+func TestNormalizeForHash(t *testing.T) {
+	all := HashNormalization{StripHTML: true, Lowercase: true, StripPunctuation: true, CollapseWhitespace: true}
+
+	tests := []struct {
+		name    string
+		n       HashNormalization
+		content string
+		want    string
+	}{
+		{"精确匹配只处理换行与首尾空白", HashNormalization{}, "  Hello,\r\nWorld!  ", "Hello, World!"},
+		{"精确匹配保留连续空白", HashNormalization{}, "a  b", "a  b"},
+		{"去除HTML标签并反转义", HashNormalization{StripHTML: true}, "<p>Tom &amp; Jerry</p>", "Tom & Jerry"},
+		{"转为小写", HashNormalization{Lowercase: true}, "Hello WORLD", "hello world"},
+		{"去除标点与符号", HashNormalization{StripPunctuation: true}, "你好，世界！$100|", "你好世界100"},
+		{"合并连续空白", HashNormalization{CollapseWhitespace: true}, " a \t b\n\nc ", "a b c"},
+		{"全部步骤", all, "<b>Breaking:</b>  NEWS!\nToday", "breaking news today"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n.NormalizeForHash(tt.content); got != tt.want {
+				t.Errorf("NormalizeForHash(%q) = %q，期望 %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHashNormalization(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    HashNormalization
+		wantErr bool
+	}{
+		{"", HashNormalization{}, false},
+		{"exact", HashNormalization{}, false},
+		{"html, Lowercase", HashNormalization{StripHTML: true, Lowercase: true}, false},
+		{"punctuation,whitespace", HashNormalization{StripPunctuation: true, CollapseWhitespace: true}, false},
+		{"all", HashNormalization{StripHTML: true, Lowercase: true, StripPunctuation: true, CollapseWhitespace: true}, false},
+		{"html,unknown", HashNormalization{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHashNormalization(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHashNormalization(%q) 错误 = %v，期望出错 %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHashNormalization(%q) = %+v，期望 %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// withHashNormalization 在测试期间替换全局标准化步骤
+func withHashNormalization(t *testing.T, n HashNormalization) {
+	t.Helper()
+	previous := GetHashNormalization()
+	SetHashNormalization(n)
+	t.Cleanup(func() { SetHashNormalization(previous) })
+}
+
+func TestContentHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     HashNormalization
+		a, b  string
+		equal bool
+	}{
+		{"精确匹配忽略换行差异", HashNormalization{}, "hello\nworld", "hello world", true},
+		{"精确匹配区分大小写", HashNormalization{}, "Hello", "hello", false},
+		{"小写后大小写不同视为相同", HashNormalization{Lowercase: true}, "Hello", "hello", true},
+		{"去除标点后标点不同视为相同", HashNormalization{StripPunctuation: true}, "你好，世界", "你好世界", true},
+		{"不同内容", HashNormalization{}, "a", "b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withHashNormalization(t, tt.n)
+			if got := ContentHash(tt.a) == ContentHash(tt.b); got != tt.equal {
+				t.Errorf("ContentHash(%q) 与 ContentHash(%q) 相同 = %v，期望 %v", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+
+	withHashNormalization(t, HashNormalization{})
+	// "hello" 的SHA-256，保证哈希算法与已入库数据一致
+	if got, want := ContentHash("hello"), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Errorf("ContentHash(\"hello\") = %s，期望 %s", got, want)
+	}
+}
+
+func TestTitleContentHash(t *testing.T) {
+	all := HashNormalization{StripHTML: true, Lowercase: true, StripPunctuation: true, CollapseWhitespace: true}
+
+	tests := []struct {
+		name          string
+		n             HashNormalization
+		title1, text1 string
+		title2, text2 string
+		equal         bool
+	}{
+		{"标题与正文边界不同（精确匹配）", HashNormalization{}, "ab", "c", "a", "bc", false},
+		{"标题与正文边界不同（去除标点）", HashNormalization{StripPunctuation: true}, "ab", "c", "a", "bc", false},
+		{"标题含分隔符字符（去除标点）", HashNormalization{StripPunctuation: true}, "a|b", "c", "a", "b|c", false},
+		{"边界不同（全部步骤）", all, "Hello World", "news", "Hello", "World news", false},
+		{"仅标点不同（去除标点）", HashNormalization{StripPunctuation: true}, "你好，世界", "正文！", "你好世界", "正文", true},
+		{"空标题与空正文不同", HashNormalization{}, "", "x", "x", "", false},
+		{"相同内容", all, "Title", "<p>Body</p>", "title", "body", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withHashNormalization(t, tt.n)
+			got := TitleContentHash(tt.title1, tt.text1) == TitleContentHash(tt.title2, tt.text2)
+			if got != tt.equal {
+				t.Errorf("(%q, %q) 与 (%q, %q) 哈希相同 = %v，期望 %v", tt.title1, tt.text1, tt.title2, tt.text2, got, tt.equal)
+			}
+		})
+	}
+
+	// 未去除标点时与已入库的 title + "|" + content 哈希保持一致
+	for _, n := range []HashNormalization{{}, {StripHTML: true, Lowercase: true, CollapseWhitespace: true}} {
+		withHashNormalization(t, n)
+		if got, want := TitleContentHash("Title", "Body"), ContentHash("Title|Body"); got != want {
+			t.Errorf("标准化 %+v 下 TitleContentHash = %s，期望与旧规则一致 %s", n, got, want)
+		}
+	}
+}
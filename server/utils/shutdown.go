@@ -10,8 +10,24 @@ import (
 	"time"
 )
 
+// defaultShutdownTimeout 关闭超时配置无效时使用的默认值
+const defaultShutdownTimeout = 30 * time.Second
+
+// ShutdownHook 关闭服务器后按顺序执行的清理步骤
+type ShutdownHook struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
 // GracefulShutdown 优雅关闭服务器
-func GracefulShutdown(srv *http.Server) {
+// 收到信号后先停止接收新请求并等待进行中的请求完成，再按顺序执行清理步骤；
+// 所有阶段共用 timeout，超时后剩余步骤被跳过；timeout 小于等于0时使用默认的30秒
+func GracefulShutdown(srv *http.Server, timeout time.Duration, hooks ...ShutdownHook) {
+	if timeout <= 0 {
+		log.Printf("关闭超时 %v 无效，使用默认值 %v", timeout, defaultShutdownTimeout)
+		timeout = defaultShutdownTimeout
+	}
+
 	// 创建系统信号接收器
 	quit := make(chan os.Signal, 1)
 	// 监听 SIGINT 和 SIGTERM 信号
@@ -19,18 +35,48 @@ func GracefulShutdown(srv *http.Server) {
 
 	// 等待信号
 	<-quit
-	log.Println("正在关闭服务器...")
+	log.Printf("正在关闭服务器（超时 %v）...", timeout)
 
 	// 创建一个带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	// 尝试优雅关闭服务器
+	// 停止接收新请求，等待进行中的请求完成
+	start := time.Now()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("服务器关闭出错：%v\n", err)
 	}
+	log.Printf("HTTP服务已停止，耗时 %v", time.Since(start))
+
+	for _, hook := range hooks {
+		if ctx.Err() != nil {
+			log.Printf("关闭超时，跳过：%s", hook.Name)
+			continue
+		}
+		runShutdownHook(ctx, hook)
+	}
 
-	// 等待上下文完成
-	<-ctx.Done()
 	log.Println("服务器已关闭")
-}
\ No newline at end of file
+}
+
+// runShutdownHook 执行单个清理步骤，超时后不再等待其完成
+func runShutdownHook(ctx context.Context, hook ShutdownHook) {
+	log.Printf("正在停止：%s", hook.Name)
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Printf("停止%s出错：%v", hook.Name, err)
+			return
+		}
+		log.Printf("已停止：%s，耗时 %v", hook.Name, time.Since(start))
+	case <-ctx.Done():
+		log.Printf("停止%s超时", hook.Name)
+	}
+}
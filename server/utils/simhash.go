@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"unicode"
+)
+
+const (
+	// SimHashBandCount SimHash指纹切分的band数量（64位切成4段，每段16位）
+	SimHashBandCount = 4
+	simHashBandBits  = 64 / SimHashBandCount
+)
+
+// SimHash 计算文本的64位SimHash指纹
+// 以相邻字符二元组为特征，兼顾中文（无空格分词）与英文文本
+func SimHash(text string) uint64 {
+	var runes []rune
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, unicode.ToLower(r))
+		}
+	}
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	addFeature := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(runes) == 1 {
+		addFeature(string(runes))
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		addFeature(string(runes[i : i+2]))
+	}
+
+	var fingerprint uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// SimHashBands 将指纹切分为band键，用于建立倒排索引
+// 海明距离不超过 SimHashBandCount-1 的两个指纹至少有一个band完全相同
+func SimHashBands(fingerprint uint64) []string {
+	bands := make([]string, 0, SimHashBandCount)
+	mask := uint64(1)<<simHashBandBits - 1
+	for i := 0; i < SimHashBandCount; i++ {
+		value := (fingerprint >> uint(i*simHashBandBits)) & mask
+		bands = append(bands, fmt.Sprintf("%d:%04x", i, value))
+	}
+	return bands
+}
+
+// FormatSimHash 将指纹格式化为16位十六进制字符串
+func FormatSimHash(fingerprint uint64) string {
+	return fmt.Sprintf("%016x", fingerprint)
+}
+
+// ParseSimHash 解析十六进制格式的指纹
+func ParseSimHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// HammingDistance 计算两个指纹之间的海明距离
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
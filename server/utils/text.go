@@ -0,0 +1,14 @@
+package utils
+
+import "unicode/utf8"
+
+// TruncateRunes 按字符数截断文本并追加省略号，返回截断后的文本及是否发生截断
+// maxRunes <= 0 表示不限制
+func TruncateRunes(text string, maxRunes int) (string, bool) {
+	if maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes {
+		return text, false
+	}
+
+	runes := []rune(text)
+	return string(runes[:maxRunes]) + "…", true
+}